@@ -0,0 +1,350 @@
+package sudoku
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// This file contains strategies based on Almost Locked Sets (ALS): ALS-XZ,
+// ALS-XY-Wing and Death Blossom.
+//
+// An ALS is a group of N unsolved squares in a single unit that together have
+// exactly N+1 candidates. Removing any one of its digits turns it into a
+// locked set, where each of the remaining digits must be placed in it.
+
+// ALS is an Almost Locked Set on the board.
+type ALS struct {
+	// Cells lists the squares of the set, in increasing order.
+	Cells []Index
+
+	// Digits is the union of the candidates of Cells; it has len(Cells)+1
+	// members.
+	Digits Digits
+
+	// cells is Cells as a squareSet, byDigit[d] is the subset of cells that
+	// have d as a candidate, and seenByDigit[d] is the set of squares that see
+	// all of byDigit[d].
+	cells       squareSet
+	byDigit     [10]squareSet
+	seenByDigit [10]squareSet
+}
+
+// String implements the fmt.Stringer interface for ALS.
+func (a ALS) String() string {
+	return fmt.Sprintf("%s{%s}", squareNames(a.Cells), a.Digits)
+}
+
+// squareSet is a set of squares, represented as a bitmask.
+type squareSet [2]uint64
+
+func (s squareSet) has(sq Index) bool {
+	return s[sq/64]&(1<<(sq%64)) != 0
+}
+
+func (s *squareSet) add(sq Index) {
+	s[sq/64] |= 1 << (sq % 64)
+}
+
+func (s squareSet) and(o squareSet) squareSet {
+	return squareSet{s[0] & o[0], s[1] & o[1]}
+}
+
+func (s squareSet) or(o squareSet) squareSet {
+	return squareSet{s[0] | o[0], s[1] | o[1]}
+}
+
+func (s squareSet) andNot(o squareSet) squareSet {
+	return squareSet{s[0] &^ o[0], s[1] &^ o[1]}
+}
+
+func (s squareSet) empty() bool {
+	return s[0] == 0 && s[1] == 0
+}
+
+// squares returns the members of s in increasing order.
+func (s squareSet) squares() []Index {
+	var sqs []Index
+	for w := 0; w < 2; w++ {
+		for b := s[w]; b != 0; b &= b - 1 {
+			sqs = append(sqs, w*64+bits.TrailingZeros64(b))
+		}
+	}
+	return sqs
+}
+
+// seenByAll returns the set of squares that are peers of all the squares in s.
+func seenByAll(s squareSet) squareSet {
+	seen := squareSet{^uint64(0), ^uint64(0)}
+	for w := 0; w < 2; w++ {
+		for b := s[w]; b != 0; b &= b - 1 {
+			seen = seen.and(peerSets[w*64+bits.TrailingZeros64(b)])
+		}
+	}
+	return seen
+}
+
+// candidatesSet returns the set of unsolved squares that have digit as a
+// candidate.
+func candidatesSet(values Values, digit uint16) squareSet {
+	var s squareSet
+	for sq, d := range values {
+		if d.Size() > 1 && d.IsMember(digit) {
+			s.add(sq)
+		}
+	}
+	return s
+}
+
+// FindALSs finds all the Almost Locked Sets on the board. Sets of squares that
+// share more than one unit (e.g. a row and a box) are only reported once.
+func FindALSs(values Values) []ALS {
+	var alss []ALS
+	seen := make(map[squareSet]bool)
+
+	for _, unit := range unitlist {
+		var open []int
+		for _, sq := range unit {
+			if values[sq].Size() > 1 {
+				open = append(open, sq)
+			}
+		}
+
+		for n := 1; n < len(open); n++ {
+			forEachCombination(open, n, func(combo []int) bool {
+				var digits Digits
+				var cells squareSet
+				for _, sq := range combo {
+					digits |= values[sq]
+					cells.add(sq)
+				}
+				if digits.Size() != n+1 || seen[cells] {
+					return false
+				}
+				seen[cells] = true
+
+				als := ALS{Cells: cells.squares(), Digits: digits, cells: cells}
+				for _, sq := range combo {
+					for _, digit := range digitsOf(values[sq]) {
+						als.byDigit[digit].add(sq)
+					}
+				}
+				for _, digit := range digitsOf(digits) {
+					als.seenByDigit[digit] = seenByAll(als.byDigit[digit])
+				}
+				alss = append(alss, als)
+				return false
+			})
+		}
+	}
+	return alss
+}
+
+// restrictedCommons returns the restricted common candidates of two
+// non-overlapping ALSs a and b: digits that appear in both, where all the
+// squares with the digit in a see all the squares with the digit in b. At most
+// one of them can be placed in a and b together.
+func restrictedCommons(a, b *ALS) Digits {
+	var rccs Digits
+	common := a.Digits & b.Digits
+	for digit := uint16(1); digit <= 9; digit++ {
+		if common.IsMember(digit) && b.byDigit[digit].andNot(a.seenByDigit[digit]).empty() {
+			rccs = rccs.Add(digit)
+		}
+	}
+	return rccs
+}
+
+// alsEliminations collects the candidates for digit that can be eliminated
+// because they see all the squares with digit in alss.
+func alsEliminations(values Values, digit uint16, alss ...*ALS) []Candidate {
+	seen := squareSet{^uint64(0), ^uint64(0)}
+	var cells squareSet
+	for _, a := range alss {
+		seen = seen.and(a.seenByDigit[digit])
+		cells = cells.or(a.cells)
+	}
+	targets := seen.and(candidatesSet(values, digit)).andNot(cells)
+
+	var elims []Candidate
+	for _, sq := range targets.squares() {
+		elims = append(elims, Candidate{sq, digit})
+	}
+	return elims
+}
+
+// alsStep creates a step for an ALS-based technique.
+func alsStep(t Technique, z uint16, elims []Candidate, alss ...*ALS) *Step {
+	step := &Step{
+		Technique:    t,
+		Eliminations: elims,
+		Digits:       SingleDigitSet(z),
+	}
+	var cells squareSet
+	var names []string
+	for _, a := range alss {
+		step.ALS = append(step.ALS, *a)
+		cells = cells.or(a.cells)
+		names = append(names, a.String())
+	}
+	step.Cells = cells.squares()
+	return describe(step, "%d is seen from all of %s", z, strings.Join(names, ", "))
+}
+
+// findALSXZ finds two ALSs A and B with a restricted common candidate x. Since
+// x can't be in both, one of them is a locked set; therefore, another digit z
+// common to both must be in A or in B, and can be eliminated from squares that
+// see all the z candidates of both.
+func findALSXZ(values Values) *Step {
+	alss := FindALSs(values)
+	for i := range alss {
+		for j := i + 1; j < len(alss); j++ {
+			a, b := &alss[i], &alss[j]
+			if !a.cells.and(b.cells).empty() {
+				continue
+			}
+			rccs := restrictedCommons(a, b)
+			if rccs == 0 {
+				continue
+			}
+			for _, z := range digitsOf((a.Digits & b.Digits).RemoveAll(rccs)) {
+				if elims := alsEliminations(values, z, a, b); len(elims) > 0 {
+					return alsStep(ALSXZ, z, elims, a, b)
+				}
+			}
+			// If there are two restricted commons, each of them can be z with
+			// respect to the other.
+			if rccs.Size() == 2 {
+				for _, z := range digitsOf(rccs) {
+					if elims := alsEliminations(values, z, a, b); len(elims) > 0 {
+						return alsStep(ALSXZ, z, elims, a, b)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// findALSXYWing finds three ALSs A, B and C, where A and C have a restricted
+// common candidate x and B and C have a different restricted common y. If A
+// doesn't hold x, C holds x, so B is locked without y; similarly in reverse.
+// So at least one of A and B is a locked set, and a digit z common to both can
+// be eliminated from squares that see all their z candidates.
+func findALSXYWing(values Values) *Step {
+	alss := FindALSs(values)
+
+	// linked[i] lists the ALSs that don't overlap ALS i and have restricted
+	// commons with it, in increasing order of index.
+	type link struct {
+		als  int
+		rccs Digits
+	}
+	linked := make([][]link, len(alss))
+	for i := range alss {
+		for j := i + 1; j < len(alss); j++ {
+			if alss[i].cells.and(alss[j].cells).empty() {
+				if r := restrictedCommons(&alss[i], &alss[j]); r != 0 {
+					linked[i] = append(linked[i], link{j, r})
+					linked[j] = append(linked[j], link{i, r})
+				}
+			}
+		}
+	}
+
+	for ci := range alss {
+		for li, la := range linked[ci] {
+			for _, lb := range linked[ci][li+1:] {
+				ac, bc := la.rccs, lb.rccs
+				a, b, c := &alss[la.als], &alss[lb.als], &alss[ci]
+				if !a.cells.and(b.cells).empty() {
+					continue
+				}
+				// Find distinct x and y.
+				if ac.Size() == 1 && bc.Size() == 1 && ac == bc {
+					continue
+				}
+				for _, z := range digitsOf((a.Digits & b.Digits).RemoveAll(ac | bc)) {
+					if elims := alsEliminations(values, z, a, b); len(elims) > 0 {
+						return alsStep(ALSXYWing, z, elims, a, b, c)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// findDeathBlossom finds a "stem" square with candidates d1...dn and "petal"
+// ALSs P1...Pn, where all the di candidates in Pi see the stem. Whichever
+// digit the stem holds, the matching petal is locked; so a digit z common to
+// all petals can be eliminated from squares that see all their z candidates.
+func findDeathBlossom(values Values) *Step {
+	alss := FindALSs(values)
+
+	for stem, sd := range values {
+		if sd.Size() < 2 || sd.Size() > 3 {
+			continue
+		}
+		stemDigits := digitsOf(sd)
+
+		// petals[i] lists the ALSs that can act as the petal for stemDigits[i].
+		petals := make([][]*ALS, len(stemDigits))
+		for i, d := range stemDigits {
+			for j := range alss {
+				a := &alss[j]
+				if a.cells.has(stem) || !a.Digits.IsMember(d) {
+					continue
+				}
+				if a.byDigit[d].andNot(peerSets[stem]).empty() {
+					petals[i] = append(petals[i], a)
+				}
+			}
+		}
+
+		for z := uint16(1); z <= 9; z++ {
+			chosen := make([]*ALS, len(stemDigits))
+
+			// Choose a petal for each stem digit, keeping track of the squares
+			// that see all the z candidates so far and backtracking when there
+			// are none left.
+			var rec func(i int, targets, cells squareSet) *Step
+			rec = func(i int, targets, cells squareSet) *Step {
+				if i == len(stemDigits) {
+					targets = targets.andNot(cells)
+					if targets.empty() {
+						return nil
+					}
+					var elims []Candidate
+					for _, sq := range targets.squares() {
+						elims = append(elims, Candidate{sq, z})
+					}
+					step := alsStep(DeathBlossom, z, elims, chosen...)
+					step.Cells = append([]Index{stem}, step.Cells...)
+					return describe(step, "stem %s with petals %v", squareName(stem), step.ALS)
+				}
+				for _, p := range petals[i] {
+					if !p.Digits.IsMember(z) || z == stemDigits[i] || !p.cells.and(cells).empty() {
+						continue
+					}
+					t := targets.and(p.seenByDigit[z])
+					if t.empty() {
+						continue
+					}
+					chosen[i] = p
+					if step := rec(i+1, t, cells.or(p.cells)); step != nil {
+						return step
+					}
+				}
+				return nil
+			}
+
+			var stemCells squareSet
+			stemCells.add(stem)
+			if step := rec(0, candidatesSet(values, z), stemCells); step != nil {
+				return step
+			}
+		}
+	}
+	return nil
+}
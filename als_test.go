@@ -0,0 +1,103 @@
+package sudoku
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestFindALSs(t *testing.T) {
+	// Solve the whole board except for three squares in row 1 with candidates
+	// 12, 23 and 123.
+	v, err := ParseBoard(filled, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v[0] = Digits(0).Add(1).Add(2)
+	v[1] = Digits(0).Add(2).Add(3)
+	v[2] = Digits(0).Add(1).Add(2).Add(3)
+
+	alss := FindALSs(v)
+
+	// Each single square with two candidates is an ALS, as well as every pair of
+	// squares with three candidates between them. The three squares are both in
+	// row 1 and in box 1, but each set is only reported once.
+	var got []string
+	for _, als := range alss {
+		got = append(got, als.String())
+	}
+	want := []string{"r1c1{12}", "r1c2{23}", "r1c1,r1c2{123}", "r1c1,r1c3{123}", "r1c2,r1c3{123}"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got ALSs %v, want %v", got, want)
+	}
+}
+
+// alsBoard creates an empty board with an XY-Wing shaped pattern: the squares
+// r5c5{12}, r5c1{13} and r1c5{23}. 3 can be eliminated from r1c1 by all the
+// ALS techniques.
+func alsBoard() Values {
+	v := EmptyBoard()
+	v[40] = Digits(0).Add(1).Add(2)
+	v[36] = Digits(0).Add(1).Add(3)
+	v[4] = Digits(0).Add(2).Add(3)
+	return v
+}
+
+func checkALSStep(t *testing.T, step *Step, want Technique, wantALSs int) {
+	t.Helper()
+	if step == nil {
+		t.Fatalf("got no step, want %v", want)
+	}
+	if step.Technique != want {
+		t.Errorf("got technique %v, want %v", step.Technique, want)
+	}
+	if len(step.ALS) != wantALSs {
+		t.Errorf("got %v ALSs, want %v", len(step.ALS), wantALSs)
+	}
+	if !slices.Equal(step.Eliminations, []Candidate{{0, 3}}) {
+		t.Errorf("got eliminations %v", step.Eliminations)
+	}
+	for _, als := range step.ALS {
+		if als.Digits.Size() != len(als.Cells)+1 {
+			t.Errorf("got invalid ALS %v", als)
+		}
+		for _, sq := range als.Cells {
+			if !slices.Contains(step.Cells, sq) {
+				t.Errorf("ALS square %v isn't in step cells %v", sq, step.Cells)
+			}
+		}
+	}
+}
+
+func TestALSXZ(t *testing.T) {
+	checkALSStep(t, findALSXZ(alsBoard()), ALSXZ, 2)
+}
+
+func TestALSXYWing(t *testing.T) {
+	checkALSStep(t, findALSXYWing(alsBoard()), ALSXYWing, 3)
+}
+
+func TestDeathBlossom(t *testing.T) {
+	step := findDeathBlossom(alsBoard())
+	checkALSStep(t, step, DeathBlossom, 2)
+	if step.Cells[0] != 40 {
+		t.Errorf("got stem %v, want 40", step.Cells[0])
+	}
+}
+
+func TestDisplayStepAsSVG(t *testing.T) {
+	v := alsBoard()
+	step := findALSXZ(v)
+
+	var buf bytes.Buffer
+	DisplayStepAsSVG(&buf, v, *step)
+	out := buf.String()
+
+	for _, want := range []string{"<svg", "fill:red", "fill:" + stepHighlightColors[1], "r1c1&lt;&gt;3"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expect SVG output to contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,401 @@
+package sudoku
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// BoardFormat is a format of files with many boards, read by BoardReader and
+// written by BoardWriter.
+type BoardFormat int
+
+const (
+	// FormatAuto detects the format from the first line of the input. It's
+	// only valid for reading.
+	FormatAuto BoardFormat = iota
+
+	// FormatText has a board per line, with '.' for empty squares, optionally
+	// followed by a comment after '#':
+	//
+	//	4.....8.5.3..........7......2.....6.....8.4......1.......6.3.7.5..2.....1.4...... #1 Difficulty: 3.10
+	//
+	// When reading, boards may also span several lines, as long as they have
+	// 81 squares ('.' or '0' for empty squares) between them; other characters
+	// like separators between boxes are ignored. Lines starting with '#' are
+	// skipped, except within a board, where they're comments, and so are
+	// sections of .sdk files other than [Puzzle].
+	FormatText
+
+	// FormatGrid is like FormatText, with each board written over 9 lines and
+	// followed by an empty line.
+	FormatGrid
+
+	// FormatJSON has a puzzle per line in the JSON encoding of Puzzle. When
+	// reading, a "board" field is accepted in place of "givens".
+	FormatJSON
+
+	// FormatCSV has a header line and a puzzle per line, with the columns
+	// board, difficulty and comment. When reading, only the board column is
+	// required, and it may be named givens instead.
+	FormatCSV
+)
+
+var boardFormatNames = []string{"auto", "text", "grid", "json", "csv"}
+
+func (f BoardFormat) String() string {
+	if f < 0 || int(f) >= len(boardFormatNames) {
+		return fmt.Sprintf("BoardFormat(%d)", int(f))
+	}
+	return boardFormatNames[f]
+}
+
+// ParseBoardFormat returns the format with the given name, as returned by
+// BoardFormat.String.
+func ParseBoardFormat(name string) (BoardFormat, error) {
+	for i, n := range boardFormatNames {
+		if strings.EqualFold(n, name) {
+			return BoardFormat(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown board format %q", name)
+}
+
+// RecordError is the error returned by BoardReader for an invalid record. The
+// reader skips the record, so reading can go on after it.
+type RecordError struct {
+	// Line is the line where the record starts.
+	Line int
+	Err  error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("line %v: %v", e.Line, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// BoardReader reads puzzles one at a time from a stream of boards, so large
+// files don't need to fit in memory.
+type BoardReader struct {
+	// Format is the format of the input. If it's FormatAuto when the first
+	// puzzle is read, the format is detected and set: JSON for a first line
+	// starting with '{', CSV for a first line with a comma (before any '#'),
+	// and text otherwise.
+	Format BoardFormat
+
+	scanner *bufio.Scanner
+	lineno  int
+	line    int
+
+	// Text state: the section of an .sdk file, and a line that starts the next
+	// board after a board with too few squares.
+	section string
+	pending string
+
+	// CSV state: the indices of the columns.
+	columns map[string]int
+}
+
+// NewBoardReader returns a reader of boards from r, detecting their format.
+func NewBoardReader(r io.Reader) *BoardReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	return &BoardReader{scanner: scanner, section: "puzzle"}
+}
+
+// Line returns the line where the last puzzle read starts.
+func (br *BoardReader) Line() int {
+	return br.line
+}
+
+// Read reads the next puzzle. Its Board holds the givens; in FormatText its
+// Comment holds the comments of its lines, joined with newlines. At the end of
+// the input Read returns io.EOF. An invalid record is reported as a
+// *RecordError, after which Read can be called again for the next puzzle.
+func (br *BoardReader) Read() (Puzzle, error) {
+	line, ok := br.nextLine()
+	if !ok {
+		return Puzzle{}, br.eof()
+	}
+	if br.Format == FormatAuto {
+		beforeComment, _, _ := strings.Cut(line, "#")
+		switch {
+		case strings.HasPrefix(line, "{"):
+			br.Format = FormatJSON
+		case strings.Contains(beforeComment, ","):
+			br.Format = FormatCSV
+		default:
+			br.Format = FormatText
+		}
+	}
+
+	switch br.Format {
+	case FormatJSON:
+		br.line = br.lineno
+		p, err := parseJSONRecord(line)
+		if err != nil {
+			return Puzzle{}, &RecordError{br.line, err}
+		}
+		return p, nil
+	case FormatCSV:
+		if br.columns == nil {
+			if err := br.readCSVHeader(line); err != nil {
+				return Puzzle{}, err
+			}
+			if line, ok = br.nextLine(); !ok {
+				return Puzzle{}, br.eof()
+			}
+		}
+		br.line = br.lineno
+		p, err := br.parseCSVRecord(line)
+		if err != nil {
+			return Puzzle{}, &RecordError{br.line, err}
+		}
+		return p, nil
+	case FormatText, FormatGrid:
+		return br.readText(line)
+	default:
+		return Puzzle{}, fmt.Errorf("unknown board format %v", br.Format)
+	}
+}
+
+// nextLine returns the next line that isn't empty or a comment; in text
+// formats, comment lines are returned too, since they may be part of a board.
+func (br *BoardReader) nextLine() (string, bool) {
+	if len(br.pending) > 0 {
+		line := br.pending
+		br.pending = ""
+		br.lineno++
+		return line, true
+	}
+	for br.scanner.Scan() {
+		br.lineno++
+		line := strings.TrimSpace(br.scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+		if strings.HasPrefix(line, "#") && br.Format != FormatText && br.Format != FormatGrid {
+			continue
+		}
+		return line, true
+	}
+	return "", false
+}
+
+func (br *BoardReader) eof() error {
+	if err := br.scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+// readText reads a board in the text formats, starting with line.
+func (br *BoardReader) readText(line string) (Puzzle, error) {
+	var squares []byte
+	var comments []string
+	for {
+		board, comment, _ := strings.Cut(line, "#")
+		board = strings.TrimSpace(board)
+		comment = strings.TrimSpace(comment)
+
+		if strings.HasPrefix(board, "[") {
+			br.section = strings.ToLower(strings.Trim(board, "[]"))
+		} else if br.section == "puzzle" {
+			lineSquares := boardSquares(board)
+			if len(lineSquares) > 0 && len(squares) == 0 {
+				br.line = br.lineno
+			}
+			if len(squares)+len(lineSquares) > 81 {
+				if len(squares) == 0 {
+					return Puzzle{}, &RecordError{br.line, fmt.Errorf("got %v squares on a line, want 81", len(lineSquares))}
+				}
+				// This line starts another board, which is read by the next
+				// call.
+				br.pending = line
+				br.lineno--
+				return Puzzle{}, &RecordError{br.line, fmt.Errorf("got only %v squares in board, want 81", len(squares))}
+			}
+			squares = append(squares, lineSquares...)
+			if len(squares) > 0 && len(comment) > 0 {
+				comments = append(comments, comment)
+			}
+			if len(squares) == 81 {
+				board, err := ParseBoard(string(squares), false)
+				if err != nil {
+					return Puzzle{}, &RecordError{br.line, err}
+				}
+				return Puzzle{Board: board, Comment: strings.Join(comments, "\n")}, nil
+			}
+		}
+
+		var ok bool
+		if line, ok = br.nextLine(); !ok {
+			if len(squares) > 0 {
+				return Puzzle{}, &RecordError{br.line, fmt.Errorf("got only %v squares in board, want 81", len(squares))}
+			}
+			return Puzzle{}, br.eof()
+		}
+	}
+}
+
+// boardSquares returns the characters of s that stand for squares.
+func boardSquares(s string) []byte {
+	var squares []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] == '.' || (s[i] >= '0' && s[i] <= '9') {
+			squares = append(squares, s[i])
+		}
+	}
+	return squares
+}
+
+// parseJSONRecord parses a puzzle in JSON, with its board in either a givens or
+// a board field.
+func parseJSONRecord(line string) (Puzzle, error) {
+	var p Puzzle
+	if err := json.Unmarshal([]byte(line), &p); err != nil {
+		return Puzzle{}, err
+	}
+	if p.Board == nil {
+		var v struct {
+			Board Values `json:"board"`
+		}
+		if err := json.Unmarshal([]byte(line), &v); err != nil {
+			return Puzzle{}, err
+		}
+		if v.Board == nil {
+			return Puzzle{}, errors.New("no givens or board in puzzle")
+		}
+		p.Board = v.Board
+	}
+	return p, nil
+}
+
+// readCSVHeader reads the header of a CSV file from line.
+func (br *BoardReader) readCSVHeader(line string) error {
+	header, err := csv.NewReader(strings.NewReader(line)).Read()
+	if err != nil {
+		return &RecordError{br.lineno, err}
+	}
+	br.columns = make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "givens" {
+			name = "board"
+		}
+		br.columns[name] = i
+	}
+	if _, ok := br.columns["board"]; !ok {
+		return &RecordError{br.lineno, errors.New("no board column in CSV header")}
+	}
+	return nil
+}
+
+// parseCSVRecord parses a puzzle from a line of a CSV file.
+func (br *BoardReader) parseCSVRecord(line string) (Puzzle, error) {
+	record, err := csv.NewReader(strings.NewReader(line)).Read()
+	if err != nil {
+		return Puzzle{}, err
+	}
+	field := func(name string) string {
+		if i, ok := br.columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var p Puzzle
+	if p.Board, err = ParseBoard(field("board"), false); err != nil {
+		return Puzzle{}, err
+	}
+	if d := field("difficulty"); len(d) > 0 {
+		if p.Difficulty, err = strconv.ParseFloat(d, 64); err != nil {
+			return Puzzle{}, err
+		}
+	}
+	p.Comment = field("comment")
+	return p, nil
+}
+
+// BoardWriter writes puzzles one at a time as a stream of boards. Writes are
+// buffered; call Flush when done.
+type BoardWriter struct {
+	format BoardFormat
+	w      *bufio.Writer
+	csv    *csv.Writer
+}
+
+// NewBoardWriter returns a writer of boards to w in the given format, which
+// must not be FormatAuto.
+func NewBoardWriter(w io.Writer, format BoardFormat) *BoardWriter {
+	bw := &BoardWriter{format: format, w: bufio.NewWriter(w)}
+	if format == FormatCSV {
+		// Errors are buffered, so they're reported by Flush.
+		bw.csv = csv.NewWriter(bw.w)
+		bw.csv.Write([]string{"board", "difficulty", "comment"})
+	}
+	return bw
+}
+
+// Write writes p: its board (the givens) in FormatText and FormatGrid, with its
+// comment on the first line, its board, difficulty and comment in FormatCSV,
+// and all of it in FormatJSON.
+func (bw *BoardWriter) Write(p Puzzle) error {
+	if len(p.Board) != 81 {
+		return fmt.Errorf("got board of %v squares, want 81", len(p.Board))
+	}
+	var line strings.Builder
+	for _, d := range p.Board {
+		line.WriteByte(squareChar(d, '.'))
+	}
+	board := line.String()
+	comment := strings.ReplaceAll(p.Comment, "\n", " ")
+
+	switch bw.format {
+	case FormatText:
+		if len(comment) > 0 {
+			board += " #" + comment
+		}
+		_, err := fmt.Fprintln(bw.w, board)
+		return err
+	case FormatGrid:
+		for row := 0; row < 9; row++ {
+			bw.w.WriteString(board[row*9 : row*9+9])
+			if row == 0 && len(comment) > 0 {
+				bw.w.WriteString(" #" + comment)
+			}
+			bw.w.WriteByte('\n')
+		}
+		return bw.w.WriteByte('\n')
+	case FormatJSON:
+		data, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		bw.w.Write(data)
+		return bw.w.WriteByte('\n')
+	case FormatCSV:
+		return bw.csv.Write([]string{board, strconv.FormatFloat(p.Difficulty, 'f', -1, 64), comment})
+	default:
+		return fmt.Errorf("can't write boards in format %v", bw.format)
+	}
+}
+
+// Flush writes any buffered puzzles to the underlying writer.
+func (bw *BoardWriter) Flush() error {
+	if bw.csv != nil {
+		bw.csv.Flush()
+		if err := bw.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return bw.w.Flush()
+}
@@ -0,0 +1,201 @@
+package sudoku
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+// readAllBoards reads all the puzzles from br, with the lines they start on
+// and the errors for invalid records.
+func readAllBoards(br *BoardReader) ([]Puzzle, []int, []error) {
+	var puzzles []Puzzle
+	var lines []int
+	var errs []error
+	for {
+		p, err := br.Read()
+		if err == io.EOF {
+			return puzzles, lines, errs
+		}
+		if err != nil {
+			errs = append(errs, err)
+			var re *RecordError
+			if !errors.As(err, &re) {
+				return puzzles, lines, errs
+			}
+			continue
+		}
+		puzzles = append(puzzles, p)
+		lines = append(lines, br.Line())
+	}
+}
+
+func TestBoardReaderText(t *testing.T) {
+	input := `# A collection of boards in different layouts.
+
+` + hardboard1 + ` #1 Difficulty: 3.10
+..3|.2.|6..
+9..|3.5|..1
+..1|8.6|4..
+-----------
+..8|1.2|9.. # a comment
+7..|...|..8
+..6|7.8|2..
+-----------
+..2|6.9|5..
+8..|2.3|..9
+..5|.1.|3..
+[Puzzle]
+` + strings.ReplaceAll(hardboard2, ".", "0") + `
+[State]
+` + easyboard1 + `
+`
+	br := NewBoardReader(strings.NewReader(input))
+	puzzles, lines, errs := readAllBoards(br)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if br.Format != FormatText {
+		t.Errorf("got format %v, want text", br.Format)
+	}
+	if !slices.Equal(lines, []int{3, 4, 16}) {
+		t.Errorf("got lines %v, want [3 4 16]", lines)
+	}
+
+	var want []Puzzle
+	for _, b := range []string{hardboard1, easyboard1, hardboard2} {
+		v, err := ParseBoard(b, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, Puzzle{Board: v})
+	}
+	want[0].Comment = "1 Difficulty: 3.10"
+	want[1].Comment = "a comment"
+	if len(puzzles) != len(want) {
+		t.Fatalf("got %v puzzles, want %v", len(puzzles), len(want))
+	}
+	for i := range want {
+		if !slices.Equal(puzzles[i].Board, want[i].Board) || puzzles[i].Comment != want[i].Comment {
+			t.Errorf("got puzzle %v: %q\n%v", i, puzzles[i].Comment, DisplayAsInput(puzzles[i].Board))
+		}
+	}
+}
+
+func TestBoardReaderErrors(t *testing.T) {
+	input := easyboard1[:50] + "\n" +
+		hardboard1 + "\n" +
+		hardboard1 + "123\n" +
+		easyboard1 + "\n" +
+		"123456789\n"
+	puzzles, lines, errs := readAllBoards(NewBoardReader(strings.NewReader(input)))
+	if len(puzzles) != 2 || !slices.Equal(lines, []int{2, 4}) {
+		t.Errorf("got %v puzzles on lines %v, want 2 on lines [2 4]", len(puzzles), lines)
+	}
+
+	wantLines := []int{1, 3, 5}
+	if len(errs) != len(wantLines) {
+		t.Fatalf("got errors %v, want errors on lines %v", errs, wantLines)
+	}
+	for i, err := range errs {
+		var re *RecordError
+		if !errors.As(err, &re) || re.Line != wantLines[i] {
+			t.Errorf("got error %v, want error on line %v", err, wantLines[i])
+		}
+	}
+
+	for _, tt := range []struct {
+		input string
+		line  int
+	}{
+		{"{\"givens\":\"123\"}\n", 2},
+		{"n,board\n1,123\n", 3},
+		{"n,difficulty\n1,2.5\n", 2},
+	} {
+		br := NewBoardReader(strings.NewReader("# comment\n" + tt.input))
+		var re *RecordError
+		if _, err := br.Read(); !errors.As(err, &re) || re.Line != tt.line {
+			t.Errorf("got err %v for %q, want error on line %v", err, tt.input, tt.line)
+		}
+	}
+}
+
+func TestBoardWriter(t *testing.T) {
+	var puzzles []Puzzle
+	for i, b := range []string{easyboard1, hardboard1} {
+		v, err := ParseBoard(b, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		puzzles = append(puzzles, Puzzle{Board: v, Difficulty: float64(i) + 1.5, Comment: "puzzle, with a comment"})
+	}
+	// Comments that span lines are written on one line, except in FormatJSON.
+	puzzles = append(puzzles, Puzzle{Board: puzzles[0].Board, Comment: "first line\nsecond, line"})
+
+	for _, format := range []BoardFormat{FormatText, FormatGrid, FormatJSON, FormatCSV} {
+		var buf bytes.Buffer
+		bw := NewBoardWriter(&buf, format)
+		for _, p := range puzzles {
+			if err := bw.Write(p); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := bw.Flush(); err != nil {
+			t.Fatal(err)
+		}
+
+		br := NewBoardReader(&buf)
+		got, _, errs := readAllBoards(br)
+		if len(errs) > 0 {
+			t.Fatalf("%v: %v", format, errs)
+		}
+		wantFormat := format
+		if format == FormatGrid {
+			wantFormat = FormatText
+		}
+		if br.Format != wantFormat {
+			t.Errorf("got format %v reading %v", br.Format, format)
+		}
+		if len(got) != len(puzzles) {
+			t.Fatalf("%v: got %v puzzles, want %v", format, len(got), len(puzzles))
+		}
+		for i, p := range got {
+			wantComment := puzzles[i].Comment
+			if format != FormatJSON {
+				wantComment = strings.ReplaceAll(wantComment, "\n", " ")
+			}
+			if !slices.Equal(p.Board, puzzles[i].Board) || p.Comment != wantComment {
+				t.Errorf("%v: got different puzzle %v after round trip: %+v", format, i, p)
+			}
+			if (format == FormatJSON || format == FormatCSV) && p.Difficulty != puzzles[i].Difficulty {
+				t.Errorf("%v: got difficulty %v, want %v", format, p.Difficulty, puzzles[i].Difficulty)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	bw := NewBoardWriter(&buf, FormatText)
+	if err := bw.Write(puzzles[1]); err != nil {
+		t.Fatal(err)
+	}
+	bw.Flush()
+	if want := hardboard1 + " #puzzle, with a comment\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestParseBoardFormat(t *testing.T) {
+	for _, f := range []BoardFormat{FormatAuto, FormatText, FormatGrid, FormatJSON, FormatCSV} {
+		got, err := ParseBoardFormat(f.String())
+		if err != nil || got != f {
+			t.Errorf("got %v, %v for %v", got, err, f)
+		}
+	}
+	if _, err := ParseBoardFormat("xml"); err == nil {
+		t.Errorf("got no error for unknown format")
+	}
+}
@@ -0,0 +1,273 @@
+package sudoku
+
+import (
+	"bytes"
+	"sync"
+)
+
+// Canonicalize returns the canonical form of a puzzle: the board that is
+// lexicographically minimal among all the boards equivalent to it under the
+// transforms of Transform, together with a transform that maps values to it.
+// Boards are compared as strings of 81 digits in row-major order, with 0 for
+// empty squares, so the canonical form has its clues as far down and right as
+// possible. Only squares with a single digit count as clues; other squares are
+// transformed too, but don't affect the canonical form.
+//
+// Two puzzles are equivalent if and only if their canonical forms are equal.
+func Canonicalize(values Values) (Values, Transform) {
+	cz := newCanonicalizer(values)
+	cz.search()
+	return cz.best[0].Apply(values), cz.best[0]
+}
+
+// Automorphisms returns the automorphism group of a puzzle or solution grid:
+// all the transforms that map its clues to themselves, starting with the
+// identity. Only squares with a single digit count as clues, and digits that
+// don't appear among them are left unchanged.
+//
+// Most puzzles only have the identity; the number of automorphisms of boards
+// with very few clues can be huge, e.g. every transform that only permutes
+// digits maps an empty board to itself.
+func Automorphisms(values Values) []Transform {
+	cz := newCanonicalizer(values)
+	cz.all = true
+	cz.search()
+
+	// All the transforms that reach the canonical form differ by an
+	// automorphism; the first of them maps back to values.
+	inverse := cz.best[0].Inverse()
+	autos := make([]Transform, len(cz.best))
+	for i, t := range cz.best {
+		autos[i] = t.Compose(inverse)
+	}
+	return autos
+}
+
+// Equivalent reports whether boards a and b are equivalent: whether some
+// transform maps the clues of a to the clues of b.
+func Equivalent(a, b Values) bool {
+	if CountHints(a) != CountHints(b) {
+		return false
+	}
+	ca, _ := Canonicalize(a)
+	cb, _ := Canonicalize(b)
+	for sq := range ca {
+		if (ca[sq].Size() == 1 || cb[sq].Size() == 1) && ca[sq] != cb[sq] {
+			return false
+		}
+	}
+	return true
+}
+
+// canonicalizer holds the state of the search for a canonical form.
+type canonicalizer struct {
+	// grids holds the clues of the board (0 for empty squares), and of its
+	// transposition.
+	grids [2][81]uint8
+
+	// Current position in the search: cur holds the relabelled rows chosen so
+	// far for the column order cols of grids[transpose].
+	cur       [81]uint8
+	transpose int
+	cols      *[9]int
+	rows      [9]int
+
+	// The best board found so far, and the transforms that reach it: only the
+	// first one, unless all is set.
+	found     bool
+	bestBoard [81]uint8
+	best      []Transform
+	all       bool
+}
+
+// newCanonicalizer returns a canonicalizer for the clues of values.
+func newCanonicalizer(values Values) *canonicalizer {
+	cz := &canonicalizer{}
+	for sq, d := range values {
+		if d.Size() == 1 {
+			r, c := sq/9, sq%9
+			cz.grids[0][sq] = uint8(d.SingleMemberDigit())
+			cz.grids[1][c*9+r] = uint8(d.SingleMemberDigit())
+		}
+	}
+	return cz
+}
+
+// transform returns the transform to the current position in the search,
+// with the given relabelling of digits.
+func (cz *canonicalizer) transform(labels [10]uint8, next uint8) Transform {
+	t := Transform{transpose: cz.transpose == 1, rows: cz.rows, cols: *cz.cols}
+	for d := 1; d <= 9; d++ {
+		label := labels[d]
+		if label == 0 {
+			// Digits that aren't clues get the unused labels, in order.
+			next++
+			label = next
+		}
+		t.digits[d-1] = uint16(label)
+	}
+	return t
+}
+
+// columnOrders returns all the orders of columns that keep stacks together:
+// permutations of the stacks, and of the columns within each stack.
+var columnOrders = sync.OnceValue(func() [][9]int {
+	var perms [][3]int
+	for a := 0; a < 3; a++ {
+		for b := 0; b < 3; b++ {
+			if b != a {
+				perms = append(perms, [3]int{a, b, 3 - a - b})
+			}
+		}
+	}
+
+	var orders [][9]int
+	for _, stacks := range perms {
+		for _, p0 := range perms {
+			for _, p1 := range perms {
+				for _, p2 := range perms {
+					within := [3][3]int{p0, p1, p2}
+					var order [9]int
+					for s := 0; s < 3; s++ {
+						for i := 0; i < 3; i++ {
+							order[s*3+i] = stacks[s]*3 + within[s][i]
+						}
+					}
+					orders = append(orders, order)
+				}
+			}
+		}
+	}
+	return orders
+})
+
+// relabelRow writes row r of the current grid in the current column order to
+// out, relabelling its digits with labels; digits without a label get the
+// next unused one.
+func (cz *canonicalizer) relabelRow(out []uint8, r int, labels *[10]uint8, next *uint8) {
+	grid := &cz.grids[cz.transpose]
+	for c, src := range cz.cols {
+		d := grid[r*9+src]
+		if d != 0 && labels[d] == 0 {
+			*next++
+			labels[d] = *next
+		}
+		out[c] = labels[d]
+	}
+}
+
+// search finds the canonical form. The first row of the result only depends
+// on the column order and the row chosen for it, so it's computed for all of
+// them first; only the column orders and rows that reach the minimal first row
+// are searched further.
+func (cz *canonicalizer) search() {
+	// Each start is a column order of one of the grids, with a row that gives
+	// the minimal first row in that order.
+	type start struct {
+		transpose int
+		cols      *[9]int
+		r         int
+		labels    [10]uint8
+		next      uint8
+	}
+	var starts []start
+	var minRow, row [9]uint8
+	orders := columnOrders()
+	for t := 0; t < 2; t++ {
+		cz.transpose = t
+		for i := range orders {
+			cz.cols = &orders[i]
+			for r := 0; r < 9; r++ {
+				s := start{transpose: t, cols: cz.cols, r: r}
+				cz.relabelRow(row[:], r, &s.labels, &s.next)
+				cmp := bytes.Compare(row[:], minRow[:])
+				if len(starts) == 0 || cmp < 0 {
+					minRow = row
+					starts = append(starts[:0], s)
+				} else if cmp == 0 {
+					starts = append(starts, s)
+				}
+			}
+		}
+	}
+
+	copy(cz.cur[:9], minRow[:])
+	for _, s := range starts {
+		cz.transpose = s.transpose
+		cz.cols = s.cols
+		cz.rows[0] = s.r
+		cz.searchRows(1, 1<<s.r, s.labels, s.next)
+	}
+}
+
+// searchRows chooses the row of the board for each output row from level on,
+// with depth-first search; usedRows is a bitmask of the rows already chosen,
+// and labels and next the relabelling of digits so far. Only the rows that
+// give the minimal output row at this level are searched further, and
+// branches whose prefix is greater than the best board found so far are
+// pruned.
+func (cz *canonicalizer) searchRows(level int, usedRows uint16, labels [10]uint8, next uint8) {
+	type candidate struct {
+		r      int
+		row    [9]uint8
+		labels [10]uint8
+		next   uint8
+	}
+	var candidates [9]candidate
+	n := 0
+	for r := 0; r < 9; r++ {
+		if usedRows&(1<<r) != 0 {
+			continue
+		}
+		if level%3 == 0 {
+			// A new band starts: its rows must all be unused.
+			if usedRows&(0b111<<(r/3*3)) != 0 {
+				continue
+			}
+		} else if r/3 != cz.rows[level-1]/3 {
+			continue
+		}
+
+		c := candidate{r: r, labels: labels, next: next}
+		cz.relabelRow(c.row[:], r, &c.labels, &c.next)
+		if n > 0 {
+			cmp := bytes.Compare(c.row[:], candidates[0].row[:])
+			if cmp > 0 {
+				continue
+			} else if cmp < 0 {
+				n = 0
+			}
+		}
+		candidates[n] = c
+		n++
+	}
+
+	copy(cz.cur[level*9:], candidates[0].row[:])
+	prefix := cz.cur[:level*9+9]
+	cmp := -1
+	if cz.found {
+		cmp = bytes.Compare(prefix, cz.bestBoard[:level*9+9])
+	}
+	if cmp > 0 || (level == 8 && cmp == 0 && !cz.all) {
+		return
+	}
+
+	for _, c := range candidates[:n] {
+		cz.rows[level] = c.r
+		if level < 8 {
+			cz.searchRows(level+1, usedRows|1<<c.r, c.labels, c.next)
+			continue
+		}
+
+		if cmp < 0 {
+			cz.found = true
+			cz.bestBoard = cz.cur
+			cz.best = cz.best[:0]
+			cmp = 0
+		}
+		cz.best = append(cz.best, cz.transform(c.labels, c.next))
+		if !cz.all {
+			return
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package sudoku
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestCanonicalize(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, board := range []string{easyboard1, hardboard1, hardboard2, filled} {
+		v, err := ParseBoard(board, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		canon, tr := Canonicalize(v)
+		if !slices.Equal(tr.Apply(v), canon) {
+			t.Errorf("returned transform doesn't map board to its canonical form")
+		}
+		if CountHints(canon) != CountHints(v) {
+			t.Errorf("got %v hints in canonical form, want %v", CountHints(canon), CountHints(v))
+		}
+
+		// All the transformations of the board have the same canonical form,
+		// which is never greater than them.
+		for i := 0; i < 10; i++ {
+			w := RandomTransform(rng).Apply(v)
+			wcanon, _ := Canonicalize(w)
+			if !slices.Equal(wcanon, canon) {
+				t.Errorf("got different canonical forms for equivalent boards:\n%v\n%v", DisplayAsInput(canon), DisplayAsInput(wcanon))
+			}
+			if compareBoards(w, canon) < 0 {
+				t.Errorf("got transformation smaller than canonical form:\n%v", DisplayAsInput(w))
+			}
+			if !Equivalent(v, w) {
+				t.Errorf("got boards not equivalent")
+			}
+		}
+	}
+}
+
+func TestCanonicalizeFirstRow(t *testing.T) {
+	// Every row and column of this board has at least two clues. The first row
+	// of its canonical form has three, all in one stack, which sorts before the
+	// rows with the fewest clues.
+	v, err := ParseBoard("34....618..6.5....172..49....8.4.19.75.9.8.......3..8.567.........5.3..998......2", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ParseBoard("......123.....4.56.27..5......386.79..95..4812.....3...56....127...6....9..85.6..", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := Canonicalize(v)
+	if compareBoards(got, want) != 0 {
+		t.Errorf("got canonical form\n%v\nwant\n%v", DisplayAsInput(got), DisplayAsInput(want))
+	}
+	if !Equivalent(v, want) {
+		t.Errorf("got board not equivalent to its canonical form")
+	}
+}
+
+func TestEquivalent(t *testing.T) {
+	a, err := ParseBoard(hardboard1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ParseBoard(hardboard2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Equivalent(a, b) {
+		t.Errorf("got different boards equivalent")
+	}
+
+	// Moving a single clue breaks equivalence, even with the same number of
+	// clues.
+	c := slices.Clone(a)
+	c[1], c[2] = c[0], FullDigitsSet()
+	c[0] = FullDigitsSet()
+	if CountHints(c) != CountHints(a) || Equivalent(a, c) {
+		t.Errorf("got boards with moved clue equivalent")
+	}
+
+	// Candidates in empty squares don't matter.
+	d := slices.Clone(a)
+	d[80] = SingleDigitSet(1).Add(2)
+	if !Equivalent(a, RandomTransform(rand.New(rand.NewSource(2))).Apply(d)) {
+		t.Errorf("got board with candidates not equivalent")
+	}
+}
+
+// compareBoards compares the clues of boards a and b as strings of digits,
+// with 0 for empty squares.
+func compareBoards(a, b Values) int {
+	digit := func(d Digits) uint16 {
+		if d.Size() == 1 {
+			return d.SingleMemberDigit()
+		}
+		return 0
+	}
+	for sq := range a {
+		if da, db := digit(a[sq]), digit(b[sq]); da != db {
+			if da < db {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func BenchmarkCanonicalize(b *testing.B) {
+	v, err := ParseBoard(hardboard1, false)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < b.N; i++ {
+		Canonicalize(v)
+	}
+}
+
+func TestAutomorphisms(t *testing.T) {
+	// A grid made of shifted rows has many automorphisms.
+	var pattern strings.Builder
+	for r := 0; r < 9; r++ {
+		for c := 0; c < 9; c++ {
+			fmt.Fprintf(&pattern, "%d", (r*3+r/3+c)%9+1)
+		}
+	}
+
+	for _, tt := range []struct {
+		board string
+		min   int
+	}{
+		{hardboard1, 1},
+		{filled, 1},
+		{pattern.String(), 2},
+	} {
+		v, err := ParseBoard(tt.board, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		autos := Automorphisms(v)
+		if len(autos) < tt.min {
+			t.Errorf("got %v automorphisms, want at least %v", len(autos), tt.min)
+		}
+		if autos[0] != identityTransform() {
+			t.Errorf("got first automorphism %+v, want identity", autos[0])
+		}
+
+		// The automorphisms are distinct, map the board to itself and are
+		// closed under composition.
+		for i, a := range autos {
+			if !slices.Equal(a.Apply(v), v) {
+				t.Errorf("automorphism %v doesn't map board to itself", i)
+			}
+			if slices.Contains(autos[:i], a) {
+				t.Errorf("got duplicate automorphism %v", i)
+			}
+		}
+		if len(autos) > 1 {
+			composed := autos[1].Compose(autos[len(autos)-1])
+			if !slices.Contains(autos, composed) {
+				t.Errorf("automorphisms aren't closed under composition")
+			}
+		}
+	}
+}
@@ -0,0 +1,532 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eliben/go-sudoku"
+)
+
+var outFlag = flag.String("out", "", "file name for the fitted weights; stdout if empty")
+var formatFlag = flag.String("format", "auto", "input format: auto, text, grid, json, csv (see sudoku.BoardFormat)")
+var logFlag = flag.Bool("log", false, "fit the logarithm of the labels (useful for solve times)")
+var seedFlag = flag.Int64("seed", 1, "seed for the randomized searches of difficulty evaluation")
+var iterationsFlag = flag.Int("iterations", 50, "number of randomized searches for difficulty evaluation")
+
+func main() {
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintln(out, "usage: calibrate [options] <labelled boards in stdin>")
+		fmt.Fprintln(out, "")
+		fmt.Fprintln(out, "Reads boards in any format of sudoku.BoardReader, each labelled with a")
+		fmt.Fprintln(out, "number (e.g. the human solve time): the difficulty field in CSV and JSON,")
+		fmt.Fprintln(out, "and the comment after the board in text, as in")
+		fmt.Fprintln(out, "")
+		fmt.Fprintln(out, "  4.....8.5.3..........7......2.....6.....8.4......1.......6.3.7.5..2.....1.4...... #312")
+		fmt.Fprintln(out, "")
+		fmt.Fprintln(out, "Fits the weights and sub-score thresholds of EvaluateDifficulty to the")
+		fmt.Fprintln(out, "labels and writes them in the format read by sudoku.LoadDifficultyWeights.")
+		fmt.Fprintln(out, "Options:")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	format, err := sudoku.ParseBoardFormat(*formatFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	br := sudoku.NewBoardReader(os.Stdin)
+	br.Format = format
+	boards, labels := readLabelledBoards(br)
+	if len(boards) < 2 {
+		log.Fatalf("need at least 2 labelled boards, got %v", len(boards))
+	}
+	if *logFlag {
+		for i, label := range labels {
+			if label <= 0 {
+				log.Fatalf("cannot take logarithm of label %v", label)
+			}
+			labels[i] = math.Log(label)
+		}
+	}
+
+	// Evaluate the factors of each board; with a seeded mode these don't
+	// depend on the weights, so they're only computed once and rescored.
+	opts := sudoku.DifficultyOptions{
+		Mode:       sudoku.SearchSeeded,
+		Seed:       *seedFlag,
+		Iterations: *iterationsFlag,
+	}
+	reports := make([]sudoku.DifficultyReport, len(boards))
+	for i, board := range boards {
+		reports[i], err = sudoku.EvaluateDifficultyReport(board, opts)
+		if err != nil {
+			log.Fatalf("board %v: %v", i+1, err)
+		}
+	}
+
+	fitted, err := fitWeights(reports, labels)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	defaults := sudoku.DefaultDifficultyWeights
+	before := spearman(scores(reports, defaults), labels)
+	after := spearman(scores(reports, fitted), labels)
+	fmt.Fprintf(os.Stderr, "Boards: %v\n", len(boards))
+	fmt.Fprintf(os.Stderr, "%-26s %8s %8s  %-18s %-18s\n", "factor", "before", "after", "thresholds before", "thresholds after")
+	for i, f := range factors {
+		fmt.Fprintf(os.Stderr, "%-26s %8.3f %8.3f  %-18s %-18s\n", f.name,
+			weightsSlice(defaults)[i], weightsSlice(fitted)[i],
+			formatThresholds(f, defaults.Thresholds), formatThresholds(f, fitted.Thresholds))
+	}
+	fmt.Fprintf(os.Stderr, "%-26s %8.3f %8.3f\n", "Spearman correlation", before, after)
+
+	out := os.Stdout
+	if len(*outFlag) > 0 {
+		out, err = os.Create(*outFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer out.Close()
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(fitted); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// readLabelledBoards reads boards and their labels from br. Invalid boards and
+// boards without a label are reported and skipped.
+func readLabelledBoards(br *sudoku.BoardReader) ([]sudoku.Values, []float64) {
+	var boards []sudoku.Values
+	var labels []float64
+	for {
+		p, err := br.Read()
+		if err == io.EOF {
+			return boards, labels
+		}
+		var recordErr *sudoku.RecordError
+		if errors.As(err, &recordErr) {
+			log.Print(err)
+			continue
+		} else if err != nil {
+			log.Fatal(err)
+		}
+
+		label, err := puzzleLabel(p)
+		if err != nil {
+			log.Printf("line %v: %v", br.Line(), err)
+			continue
+		}
+		boards = append(boards, p.Board)
+		labels = append(labels, label)
+	}
+}
+
+// puzzleLabel returns the label of p: its difficulty if it has one, and
+// otherwise the number in its comment.
+func puzzleLabel(p sudoku.Puzzle) (float64, error) {
+	if p.Difficulty != 0 {
+		return p.Difficulty, nil
+	}
+	comment := strings.TrimSpace(p.Comment)
+	if len(comment) == 0 {
+		return 0, fmt.Errorf("board has no label")
+	}
+	label, err := strconv.ParseFloat(comment, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid label %q", comment)
+	}
+	return label, nil
+}
+
+// A factor is one of the factors of the difficulty score, with the accessors
+// of its value in a report and of its thresholds.
+type factor struct {
+	name  string
+	value func(r sudoku.DifficultyReport) float64
+
+	// Thresholds of hint counts are in decreasing order, and the sub-score
+	// goes up for values at most a threshold; thresholds of searches are in
+	// increasing order, and the sub-score goes up for values above the first
+	// one and at least the others.
+	decreasing bool
+	get        func(t *sudoku.DifficultyThresholds, k int) float64
+	set        func(t *sudoku.DifficultyThresholds, k int, v float64)
+}
+
+// hintsFactor returns the factor of a number of hints.
+func hintsFactor(name string, value func(r sudoku.DifficultyReport) int, thresholds func(t *sudoku.DifficultyThresholds) *[4]int) factor {
+	return factor{
+		name:       name,
+		value:      func(r sudoku.DifficultyReport) float64 { return float64(value(r)) },
+		decreasing: true,
+		get:        func(t *sudoku.DifficultyThresholds, k int) float64 { return float64(thresholds(t)[k]) },
+		set:        func(t *sudoku.DifficultyThresholds, k int, v float64) { thresholds(t)[k] = int(v) },
+	}
+}
+
+// factors are the factors of the difficulty score, in the order of
+// weightsSlice.
+var factors = []factor{
+	hintsFactor("hints before elimination",
+		func(r sudoku.DifficultyReport) int { return r.HintsBeforeElimination },
+		func(t *sudoku.DifficultyThresholds) *[4]int { return &t.HintsBeforeElimination }),
+	hintsFactor("hints after elimination",
+		func(r sudoku.DifficultyReport) int { return r.HintsAfterElimination },
+		func(t *sudoku.DifficultyThresholds) *[4]int { return &t.HintsAfterElimination }),
+	hintsFactor("min row/col hints",
+		func(r sudoku.DifficultyReport) int { return r.MinRowColHints },
+		func(t *sudoku.DifficultyThresholds) *[4]int { return &t.MinRowColHints }),
+	{
+		name:  "average searches",
+		value: func(r sudoku.DifficultyReport) float64 { return r.AverageSearches },
+		get:   func(t *sudoku.DifficultyThresholds, k int) float64 { return t.Searches[k] },
+		set:   func(t *sudoku.DifficultyThresholds, k int, v float64) { t.Searches[k] = v },
+	},
+}
+
+// formatThresholds returns the thresholds of factor f in t as a string.
+func formatThresholds(f factor, t sudoku.DifficultyThresholds) string {
+	var parts []string
+	for k := 0; k < 4; k++ {
+		parts = append(parts, strconv.FormatFloat(f.get(&t, k), 'g', 4, 64))
+	}
+	return strings.Join(parts, " ")
+}
+
+// maxCandidates is the maximal number of values tried for each threshold.
+const maxCandidates = 100
+
+// candidates returns the values worth trying for the thresholds of factor f:
+// the values of the factor in reports, which split them in all the possible
+// ways, and values beyond all of them so that a sub-score can be left empty.
+// If there are too many, evenly spaced quantiles of them are returned.
+func candidates(f factor, reports []sudoku.DifficultyReport) []float64 {
+	seen := make(map[float64]bool)
+	var values []float64
+	for _, r := range reports {
+		if v := f.value(r); !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	sort.Float64s(values)
+	if len(values) > maxCandidates {
+		quantiles := make([]float64, maxCandidates)
+		for i := range quantiles {
+			quantiles[i] = values[i*(len(values)-1)/(maxCandidates-1)]
+		}
+		values = quantiles
+	}
+
+	if f.decreasing {
+		return append([]float64{values[0] - 1}, values...)
+	}
+	return append(append([]float64{0}, values...), values[len(values)-1]+1)
+}
+
+// fitWeights fits the weights and thresholds of the difficulty score to
+// labels, by coordinate descent on the thresholds: each threshold in turn is
+// moved to the candidate value (keeping the thresholds in order) that gives
+// the smallest residual of fitting the weights, until no move improves it.
+// The weights are normalized to sum to 1, so that scores stay on the 1.0-5.0
+// scale; this doesn't change the ranking of boards.
+func fitWeights(reports []sudoku.DifficultyReport, labels []float64) (sudoku.DifficultyWeights, error) {
+	thresholds := sudoku.DefaultDifficultyThresholds
+	coefs, best, err := fitThresholds(reports, labels, thresholds)
+	if err != nil {
+		return sudoku.DifficultyWeights{}, err
+	}
+
+	allCandidates := make([][]float64, len(factors))
+	for i, f := range factors {
+		allCandidates[i] = candidates(f, reports)
+	}
+
+	const maxRounds = 20
+	for round := 0; round < maxRounds; round++ {
+		improved := false
+		for i, f := range factors {
+			for k := 0; k < 4; k++ {
+				for _, c := range allCandidates[i] {
+					if !inOrder(f, &thresholds, k, c) || c == f.get(&thresholds, k) {
+						continue
+					}
+					t := thresholds
+					f.set(&t, k, c)
+					cs, residual, err := fitThresholds(reports, labels, t)
+					if err == nil && residual < best-1e-9 {
+						thresholds, coefs, best = t, cs, residual
+						improved = true
+					}
+				}
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+
+	var sum float64
+	for _, c := range coefs {
+		sum += c
+	}
+	return sudoku.DifficultyWeights{
+		HintsBeforeElimination: coefs[0] / sum,
+		HintsAfterElimination:  coefs[1] / sum,
+		MinRowColHints:         coefs[2] / sum,
+		Searches:               coefs[3] / sum,
+		Thresholds:             thresholds,
+	}, nil
+}
+
+// inOrder reports whether threshold k of factor f in t can be set to v
+// without getting out of order with its neighbors.
+func inOrder(f factor, t *sudoku.DifficultyThresholds, k int, v float64) bool {
+	lower, upper := math.Inf(-1), math.Inf(1)
+	if f.decreasing {
+		if k > 0 {
+			upper = f.get(t, k-1)
+		}
+		if k < 3 {
+			lower = f.get(t, k+1)
+		}
+	} else {
+		if k > 0 {
+			lower = f.get(t, k-1)
+		}
+		if k < 3 {
+			upper = f.get(t, k+1)
+		}
+	}
+	return v >= lower && v <= upper
+}
+
+// fitThresholds fits the weights of the sub-scores of reports with the given
+// thresholds to labels, and returns them with the residual sum of squares.
+func fitThresholds(reports []sudoku.DifficultyReport, labels []float64, thresholds sudoku.DifficultyThresholds) ([]float64, float64, error) {
+	x := features(reports, thresholds)
+	intercept, coefs, err := fitNonNegative(x, labels)
+	if err != nil {
+		return nil, 0, err
+	}
+	var residual float64
+	for i, f := range x {
+		d := labels[i] - intercept
+		for j := range f {
+			d -= coefs[j] * f[j]
+		}
+		residual += d * d
+	}
+	return coefs, residual, nil
+}
+
+// features returns the sub-scores of each report with the given thresholds,
+// in the order of weightsSlice.
+func features(reports []sudoku.DifficultyReport, thresholds sudoku.DifficultyThresholds) [][]float64 {
+	x := make([][]float64, len(reports))
+	for i, r := range reports {
+		r = r.Rescore(sudoku.DifficultyWeights{Thresholds: thresholds})
+		x[i] = []float64{
+			r.HintsBeforeEliminationScore,
+			r.HintsAfterEliminationScore,
+			r.MinRowColHintsScore,
+			r.SearchesScore,
+		}
+	}
+	return x
+}
+
+func weightsSlice(w sudoku.DifficultyWeights) []float64 {
+	return []float64{w.HintsBeforeElimination, w.HintsAfterElimination, w.MinRowColHints, w.Searches}
+}
+
+// scores computes the difficulty score of each report with the given weights.
+func scores(reports []sudoku.DifficultyReport, weights sudoku.DifficultyWeights) []float64 {
+	s := make([]float64, len(reports))
+	for i, r := range reports {
+		s[i] = r.Rescore(weights).Score
+	}
+	return s
+}
+
+// fitNonNegative fits y ≈ b + sum(coefs[j] * x[i][j]) by least squares, with
+// non-negative coefs; it returns b and coefs. Features whose coefficient comes
+// out negative are dropped one at a time, and the rest refitted.
+func fitNonNegative(x [][]float64, y []float64) (float64, []float64, error) {
+	nfeatures := len(x[0])
+	active := make([]bool, nfeatures)
+	for j := range active {
+		active[j] = true
+	}
+
+	for {
+		var cols []int
+		for j, a := range active {
+			if a {
+				cols = append(cols, j)
+			}
+		}
+		if len(cols) == 0 {
+			return 0, nil, fmt.Errorf("no feature correlates positively with the labels")
+		}
+
+		beta, err := leastSquares(x, y, cols)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		// beta[0] is the intercept.
+		worst := -1
+		for k := range cols {
+			if beta[k+1] <= 0 && (worst == -1 || beta[k+1] < beta[worst+1]) {
+				worst = k
+			}
+		}
+		if worst >= 0 {
+			active[cols[worst]] = false
+			continue
+		}
+
+		coefs := make([]float64, nfeatures)
+		for k, j := range cols {
+			coefs[j] = beta[k+1]
+		}
+		return beta[0], coefs, nil
+	}
+}
+
+// leastSquares fits y ≈ beta[0] + sum(beta[k+1] * x[i][cols[k]]) by solving
+// the normal equations, and returns beta. Features that are constant over the
+// data can't be told apart from the intercept; they get a zero coefficient.
+func leastSquares(x [][]float64, y []float64, cols []int) ([]float64, error) {
+	n := len(cols) + 1
+	row := func(i int) []float64 {
+		r := []float64{1}
+		for _, j := range cols {
+			r = append(r, x[i][j])
+		}
+		return r
+	}
+
+	// Build the augmented matrix [X'X | X'y].
+	a := make([][]float64, n)
+	for k := range a {
+		a[k] = make([]float64, n+1)
+	}
+	for i := range x {
+		r := row(i)
+		for k := 0; k < n; k++ {
+			for l := 0; l < n; l++ {
+				a[k][l] += r[k] * r[l]
+			}
+			a[k][n] += r[k] * y[i]
+		}
+	}
+
+	// Gaussian elimination with partial pivoting; singular columns get a zero
+	// coefficient.
+	const eps = 1e-9
+	pivotRow := make([]int, n)
+	r := 0
+	for c := 0; c < n; c++ {
+		pivotRow[c] = -1
+		if r >= n {
+			continue
+		}
+		best := r
+		for k := r; k < n; k++ {
+			if math.Abs(a[k][c]) > math.Abs(a[best][c]) {
+				best = k
+			}
+		}
+		if math.Abs(a[best][c]) < eps {
+			continue
+		}
+		a[r], a[best] = a[best], a[r]
+		for k := 0; k < n; k++ {
+			if k != r && a[k][c] != 0 {
+				f := a[k][c] / a[r][c]
+				for l := c; l <= n; l++ {
+					a[k][l] -= f * a[r][l]
+				}
+			}
+		}
+		pivotRow[c] = r
+		r++
+	}
+
+	beta := make([]float64, n)
+	for c := 0; c < n; c++ {
+		if pivotRow[c] >= 0 {
+			beta[c] = a[pivotRow[c]][n] / a[pivotRow[c]][c]
+		}
+	}
+	if pivotRow[0] < 0 {
+		return nil, fmt.Errorf("cannot fit labels")
+	}
+	return beta, nil
+}
+
+// spearman returns the Spearman rank correlation coefficient of a and b.
+func spearman(a, b []float64) float64 {
+	return pearson(ranks(a), ranks(b))
+}
+
+// ranks returns the rank of each element of values, averaging the ranks of
+// ties.
+func ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return values[order[i]] < values[order[j]]
+	})
+
+	r := make([]float64, len(values))
+	for i := 0; i < len(order); {
+		j := i
+		for j < len(order) && values[order[j]] == values[order[i]] {
+			j++
+		}
+		for k := i; k < j; k++ {
+			r[order[k]] = float64(i+j+1) / 2
+		}
+		i = j
+	}
+	return r
+}
+
+// pearson returns the Pearson correlation coefficient of a and b, or 0 if
+// either of them is constant.
+func pearson(a, b []float64) float64 {
+	n := float64(len(a))
+	var meanA, meanB float64
+	for i := range a {
+		meanA += a[i] / n
+		meanB += b[i] / n
+	}
+	var cov, varA, varB float64
+	for i := range a {
+		cov += (a[i] - meanA) * (b[i] - meanB)
+		varA += (a[i] - meanA) * (a[i] - meanA)
+		varB += (b[i] - meanB) * (b[i] - meanB)
+	}
+	if varA == 0 || varB == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varA*varB)
+}
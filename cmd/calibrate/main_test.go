@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/eliben/go-sudoku"
+	"golang.org/x/exp/slices"
+)
+
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestLeastSquares(t *testing.T) {
+	// y = 1 + 2*x0 + 3*x1 exactly.
+	x := [][]float64{{0, 0}, {1, 0}, {0, 1}, {1, 1}, {2, 3}}
+	var y []float64
+	for _, r := range x {
+		y = append(y, 1+2*r[0]+3*r[1])
+	}
+	beta, err := leastSquares(x, y, []int{0, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{1, 2, 3}
+	for i := range want {
+		if !closeTo(beta[i], want[i]) {
+			t.Errorf("got beta %v, want %v", beta, want)
+			break
+		}
+	}
+
+	// Only the selected column is fitted; a constant one gets a zero
+	// coefficient.
+	constant := [][]float64{{5, 0}, {5, 1}, {5, 2}}
+	beta, err = leastSquares(constant, []float64{1, 3, 5}, []int{0, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if beta[1] != 0 || !closeTo(beta[2], 2) || !closeTo(beta[0], 1) {
+		t.Errorf("got beta %v, want [1 0 2]", beta)
+	}
+}
+
+func TestFitNonNegative(t *testing.T) {
+	// y = 2*x0 - x1 + 0.5: the negative coefficient is dropped, and x0 alone
+	// refitted.
+	x := [][]float64{{1, 1}, {2, 3}, {3, 2}, {4, 5}, {5, 4}}
+	var y []float64
+	for _, r := range x {
+		y = append(y, 2*r[0]-r[1]+0.5)
+	}
+	_, coefs, err := fitNonNegative(x, y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if coefs[0] <= 0 || coefs[1] != 0 {
+		t.Errorf("got coefs %v, want a positive first one and a zero second one", coefs)
+	}
+
+	// Labels that only go down with the features can't be fitted.
+	if _, _, err := fitNonNegative([][]float64{{1}, {2}, {3}}, []float64{3, 2, 1}); err == nil {
+		t.Errorf("got no error fitting decreasing labels")
+	}
+}
+
+func TestRanks(t *testing.T) {
+	got := ranks([]float64{10, 30, 20, 30, 5})
+	want := []float64{2, 4.5, 3, 4.5, 1}
+	if !slices.Equal(got, want) {
+		t.Errorf("got ranks %v, want %v", got, want)
+	}
+}
+
+func TestSpearman(t *testing.T) {
+	a := []float64{1, 2, 3, 4, 5}
+	for _, test := range []struct {
+		b    []float64
+		want float64
+	}{
+		{[]float64{1, 4, 9, 16, 25}, 1},
+		{[]float64{50, 40, 30, 20, 10}, -1},
+		{[]float64{7, 7, 7, 7, 7}, 0},
+		{[]float64{1, 3, 2, 4, 5}, 0.9},
+	} {
+		if got := spearman(a, test.b); !closeTo(got, test.want) {
+			t.Errorf("got correlation %v with %v, want %v", got, test.b, test.want)
+		}
+	}
+}
+
+func TestFitWeights(t *testing.T) {
+	// The labels only depend on whether the board has at most 30 hints, so
+	// the fit puts a threshold there: at 30 hints before elimination, or
+	// equivalently at 40 after it.
+	var reports []sudoku.DifficultyReport
+	var labels []float64
+	for hints := 24; hints <= 40; hints++ {
+		reports = append(reports, sudoku.DifficultyReport{
+			HintsBeforeElimination: hints,
+			HintsAfterElimination:  hints + 10,
+			MinRowColHints:         2,
+			AverageSearches:        1,
+			Iterations:             1,
+		})
+		label := 1.0
+		if hints <= 30 {
+			label = 2.0
+		}
+		labels = append(labels, label)
+	}
+
+	weights, err := fitWeights(reports, labels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(weights.Thresholds.HintsBeforeElimination[:], 30) &&
+		!slices.Contains(weights.Thresholds.HintsAfterElimination[:], 40) {
+		t.Errorf("got thresholds %+v, want one at 30 hints before or 40 hints after elimination", weights.Thresholds)
+	}
+	if got := spearman(scores(reports, weights), labels); !closeTo(got, spearman(labels, labels)) {
+		t.Errorf("got correlation %v after fitting", got)
+	}
+
+	// The fitted weights load back.
+	var sb strings.Builder
+	enc := json.NewEncoder(&sb)
+	if err := enc.Encode(weights); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := sudoku.LoadDifficultyWeights(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded != weights {
+		t.Errorf("got weights %+v after loading, want %+v", loaded, weights)
+	}
+}
+
+func TestPuzzleLabel(t *testing.T) {
+	for _, test := range []struct {
+		p    sudoku.Puzzle
+		want float64
+	}{
+		{sudoku.Puzzle{Difficulty: 2.5, Comment: "7"}, 2.5},
+		{sudoku.Puzzle{Comment: " 312 "}, 312},
+	} {
+		got, err := puzzleLabel(test.p)
+		if err != nil || got != test.want {
+			t.Errorf("got label %v, err %v for %+v, want %v", got, err, test.p, test.want)
+		}
+	}
+	for _, p := range []sudoku.Puzzle{{}, {Comment: "1 Difficulty: 3.10"}} {
+		if _, err := puzzleLabel(p); err == nil {
+			t.Errorf("got no error for %+v", p)
+		}
+	}
+}
@@ -1,23 +1,38 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"math/rand"
 	"os"
+	"os/signal"
+	"strings"
 	"time"
 
 	"github.com/eliben/go-sudoku"
 )
 
-// Note: trying to generate difficult boards with low hintcount may take a long
-// time.
+// Note: trying to generate difficult boards with a high -minhints or easy boards
+// with a low -hintcount may take a long time, or never finish; use -timeout to
+// limit it.
 
-var symFlag = flag.Bool("sym", false, "generate a symmetrical puzzle")
+var symFlag = flag.Bool("sym", false, "generate a symmetrical puzzle; same as -symmetry rot180")
+var symmetryFlag = flag.String("symmetry", "none", "symmetry of hints: none, rot180, rot90, horizontal, vertical, diagonal, antidiagonal, dihedral")
 var diffFlag = flag.Float64("diff", 2.5, "minimal difficulty for generated puzzle")
-var hintCountFlag = flag.Int("hintcount", 28, "hint count for generation; higher counts lead to easier puzzles")
+var maxDiffFlag = flag.Float64("maxdiff", 5.0, "maximal difficulty for generated puzzle")
+var hintCountFlag = flag.Int("hintcount", 28, "hint count for generation: hints are removed until at most this many are left; higher counts lead to easier puzzles, and 0 removes as many as the difficulty range allows")
+var minHintsFlag = flag.Int("minhints", 0, "minimal hint count for generation")
+var minimalFlag = flag.Bool("minimal", false, "generate a minimal puzzle, where every hint is needed")
+var timeoutFlag = flag.Duration("timeout", 0, "time limit for generation, e.g. 10s; no limit if 0")
+var techniquesFlag = flag.String("techniques", "", "comma-separated techniques the puzzle must need (e.g. x-wing); overrides -diff and -maxdiff")
+var maskFlag = flag.String("mask", "", "file with a mask of clue squares (see sudoku.ParseMask); overrides other generation flags except -timeout")
 var svgOutFlag = flag.String("svgout", "", "file name for SVG output, if needed")
+var countFlag = flag.Int("count", 0, "number of distinct puzzles to generate in parallel, one per line; if 0, a single puzzle is displayed")
+var outFlag = flag.String("out", "", "file name for the puzzles generated with -count; stdout if empty")
+var formatFlag = flag.String("format", "text", "output format with -count: text, grid, json or csv (see sudoku.BoardFormat)")
 
 func main() {
 	flag.Usage = func() {
@@ -30,47 +45,137 @@ func main() {
 
 	rand.Seed(time.Now().UnixNano())
 
-	count := 0
-	maxDifficultySeen := 0.0
+	symmetry, err := sudoku.ParseSymmetry(*symmetryFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *symFlag {
+		symmetry = sudoku.SymmetryRotational180
+	}
 
-	for {
-		var board sudoku.Values
+	opts := sudoku.GenerateOptions{
+		Symmetry: symmetry,
+		MinHints: *minHintsFlag,
+		MaxHints: *hintCountFlag,
+		Minimal:  *minimalFlag,
+		Timeout:  *timeoutFlag,
+	}
 
-		if *symFlag {
-			board = sudoku.GenerateSymmetrical(*hintCountFlag)
-		} else {
-			board = sudoku.Generate(*hintCountFlag)
+	if *countFlag > 0 {
+		if len(*maskFlag) > 0 || len(*techniquesFlag) > 0 || len(*svgOutFlag) > 0 {
+			log.Fatal("-count cannot be used with -mask, -techniques or -svgout")
 		}
+		generateBatch(*countFlag, opts)
+		return
+	}
+
+	var res sudoku.GenerateResult
+	if len(*maskFlag) > 0 {
+		res, err = sudoku.GenerateFromMask(readMask(*maskFlag), opts)
+	} else if len(*techniquesFlag) > 0 {
+		var req sudoku.TechniqueRequirement
+		for _, name := range strings.Split(*techniquesFlag, ",") {
+			t, err := sudoku.ParseTechnique(strings.TrimSpace(name))
+			if err != nil {
+				log.Fatal(err)
+			}
+			req.Required = append(req.Required, t)
+		}
+		res, err = sudoku.GenerateWithTechniques(req, opts)
+	} else {
+		res, err = sudoku.GenerateWithDifficulty(*diffFlag, *maxDiffFlag, opts)
+	}
+	if err != nil {
+		log.Fatalf("%v (tried %v boards)", err, res.Tried)
+	}
 
-		d, err := sudoku.EvaluateDifficulty(board)
+	fmt.Println(sudoku.DisplayAsInput(res.Board))
+	fmt.Printf("Difficulty: %.2f\n", res.Difficulty)
+	fmt.Printf("ID: %s\n", sudoku.Encode(res.Board))
+	if syms := sudoku.Symmetries(res.Board); len(syms) > 0 {
+		fmt.Printf("Symmetries: %v\n", syms)
+	}
+	fmt.Printf("Tried %v boards\n", res.Tried)
+	if len(*techniquesFlag) > 0 {
+		for i, step := range res.Techniques.Steps {
+			fmt.Printf("%3d. %s\n", i+1, step.Description)
+		}
+	}
+
+	if len(*svgOutFlag) > 0 {
+		f, err := os.Create(*svgOutFlag)
 		if err != nil {
 			log.Fatal(err)
 		}
+		defer f.Close()
+		sudoku.DisplayAsSVG(f, res.Board, res.Difficulty)
+		fmt.Println("Wrote SVG output to", *svgOutFlag)
+	}
+}
 
-		if d >= *diffFlag {
-			fmt.Println(sudoku.DisplayAsInput(board))
-			fmt.Printf("Difficulty: %.2f\n", d)
-
-			if len(*svgOutFlag) > 0 {
-				f, err := os.Create(*svgOutFlag)
-				if err != nil {
-					log.Fatal(err)
-				}
-				defer f.Close()
-				sudoku.DisplayAsSVG(f, board, d)
-				fmt.Println("Wrote SVG output to", *svgOutFlag)
-			}
+// readMask reads a mask of clue squares from the given file.
+func readMask(filename string) [81]bool {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mask, err := sudoku.ParseMask(string(data))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return mask
+}
 
-			break
-		} else {
-			count++
-			if d > maxDifficultySeen {
-				maxDifficultySeen = d
-			}
+// generateBatch generates count distinct puzzles in parallel and writes them
+// to the output file in the format selected by -format. Interrupting it
+// writes the puzzles found so far.
+func generateBatch(count int, opts sudoku.GenerateOptions) {
+	var out io.Writer = os.Stdout
+	if len(*outFlag) > 0 {
+		f, err := os.Create(*outFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		out = f
+	}
+	format, err := sudoku.ParseBoardFormat(*formatFlag)
+	if err != nil || format == sudoku.FormatAuto {
+		flag.Usage()
+		log.Fatal("Please select one of the supported output formats.")
+	}
+	w := sudoku.NewBoardWriter(out, format)
 
-			if count > 0 && count%10 == 0 {
-				fmt.Printf("Tried %v boards; max difficulty seen %.2f\n", count, maxDifficultySeen)
-			}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	puzzles, err := sudoku.GenerateMany(ctx, count, sudoku.GenerateManyOptions{
+		MinDifficulty: *diffFlag,
+		MaxDifficulty: *maxDiffFlag,
+		Generate:      opts,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	n := 0
+	for p := range puzzles.C {
+		n++
+		// The text formats number the puzzles in their comment; the others
+		// have a field for the difficulty.
+		if format == sudoku.FormatText || format == sudoku.FormatGrid {
+			p.Comment = fmt.Sprintf("%d Difficulty: %.2f", n, p.Difficulty)
+		}
+		if err := w.Write(p); err != nil {
+			log.Fatal(err)
 		}
 	}
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+	if err := puzzles.Err(); err != nil {
+		log.Fatal(err)
+	}
+	if n < count {
+		log.Printf("generated %v of %v puzzles", n, count)
+	}
 }
@@ -1,13 +1,13 @@
 package main
 
 import (
-	"bufio"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"math/rand"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/eliben/go-sudoku"
@@ -15,7 +15,12 @@ import (
 
 var statsFlag = flag.Bool("stats", false, "enable stats for solving")
 var randomizeFlag = flag.Bool("randomize", false, "randomize solving order")
-var actionFlag = flag.String("action", "solve", "action to perform: solve, count")
+var actionFlag = flag.String("action", "solve", "action to perform: solve, count, difficulty, minimal")
+var weightsFlag = flag.String("weights", "", "JSON file with difficulty weights (see sudoku.LoadDifficultyWeights)")
+var searchModeFlag = flag.String("searchmode", "random", "how difficulty evaluation measures searches: random, seeded, exhaustive")
+var seedFlag = flag.Int64("seed", 1, "seed for difficulty evaluation in seeded mode")
+var iterationsFlag = flag.Int("iterations", sudoku.DefaultDifficultyIterations, "number of randomized searches for difficulty evaluation")
+var formatFlag = flag.String("format", "auto", "input format: auto, text, grid, json, csv (see sudoku.BoardFormat)")
 
 func main() {
 	flag.Usage = func() {
@@ -26,18 +31,46 @@ func main() {
 	}
 	flag.Parse()
 
+	opts := sudoku.DifficultyOptions{Iterations: *iterationsFlag, Seed: *seedFlag}
+	if len(*weightsFlag) > 0 {
+		f, err := os.Open(*weightsFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts.Weights, err = sudoku.LoadDifficultyWeights(f)
+		f.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	switch *searchModeFlag {
+	case "random":
+		opts.Mode = sudoku.SearchRandom
+	case "seeded":
+		opts.Mode = sudoku.SearchSeeded
+	case "exhaustive":
+		opts.Mode = sudoku.SearchExhaustive
+	default:
+		flag.Usage()
+		log.Fatal("Please select one of the supported search modes.")
+	}
+
 	switch *actionFlag {
 	case "solve":
-		solveAndReport()
+		solveAndReport(opts)
 	case "count":
 		countHints()
+	case "difficulty":
+		reportDifficulty(opts)
+	case "minimal":
+		reportMinimal()
 	default:
 		flag.Usage()
 		log.Fatal("Please select one of the supported actions.")
 	}
 }
 
-func solveAndReport() {
+func solveAndReport(opts sudoku.DifficultyOptions) {
 	var totalDuration time.Duration = 0
 	var maxDuration time.Duration = 0
 	var totalSearches uint64 = 0
@@ -54,26 +87,18 @@ func solveAndReport() {
 		rand.Seed(time.Now().UnixNano())
 	}
 
-	boards := getInputBoards()
-	for _, board := range boards {
+	forEachInputBoard(func(v sudoku.Values, line int) {
 		numBoards++
 
-		v, err := sudoku.ParseBoard(board, false)
-		if err != nil {
-			log.Fatal(err)
-		}
-		d, err := sudoku.EvaluateDifficulty(v)
+		report, err := sudoku.EvaluateDifficultyReport(v, opts)
 		if err != nil {
-			log.Fatal(err)
+			log.Fatalf("line %v: %v", line, err)
 		}
-		totalDifficulty += d
+		totalDifficulty += report.Score
 
 		tStart := time.Now()
 		sudoku.EliminateAll(v)
 		v, _ = sudoku.Solve(v, sudoku.SolveOptions{Randomize: *randomizeFlag})
-		if err != nil {
-			log.Fatal(err)
-		}
 		tElapsed := time.Now().Sub(tStart)
 
 		totalDuration += tElapsed
@@ -92,7 +117,7 @@ func solveAndReport() {
 			}
 			sudoku.Stats.Reset()
 		}
-	}
+	})
 
 	fmt.Printf("Solved %v/%v boards\n", numSolved, numBoards)
 	fmt.Printf("Average difficulty: %.2v\n", totalDifficulty/float64(numBoards))
@@ -103,13 +128,8 @@ func solveAndReport() {
 }
 
 func countHints() {
-	boards := getInputBoards()
-	for _, board := range boards {
-		fmt.Println("board:", board)
-		v, err := sudoku.ParseBoard(board, false)
-		if err != nil {
-			log.Fatal(err)
-		}
+	forEachInputBoard(func(v sudoku.Values, line int) {
+		fmt.Println("board:", boardLine(v))
 		fmt.Println("|")
 
 		initialNumHints := sudoku.CountHints(v)
@@ -123,26 +143,69 @@ func countHints() {
 		afterTwinsNumHints := sudoku.CountHints(v)
 		fmt.Printf("  num hints after twins:       %v\n", afterTwinsNumHints)
 		fmt.Println("")
-	}
+	})
 }
 
-// getInputBoards reads input boards from stdin, ignores comments and empty
-// lines and returns them.
-func getInputBoards() []string {
-	var boards []string
+// reportDifficulty prints a breakdown of the difficulty score of each board.
+func reportDifficulty(opts sudoku.DifficultyOptions) {
+	forEachInputBoard(func(v sudoku.Values, line int) {
+		fmt.Println("board:", boardLine(v))
+		report, err := sudoku.EvaluateDifficultyReport(v, opts)
+		if err != nil {
+			log.Fatalf("line %v: %v", line, err)
+		}
+		fmt.Println(report)
+	})
+}
 
-	scanner := bufio.NewScanner(os.Stdin)
-	// Expect one board per line, ignoring whitespace and lines starting with '#'.
-	for scanner.Scan() {
-		board := strings.TrimSpace(scanner.Text())
-		if len(board) == 0 || strings.HasPrefix(board, "#") {
+// reportMinimal reports whether each board is minimal, and its redundant
+// hints otherwise.
+func reportMinimal() {
+	forEachInputBoard(func(v sudoku.Values, line int) {
+		board := boardLine(v)
+		minimal, redundant, err := sudoku.IsMinimal(v)
+		switch {
+		case err != nil:
+			fmt.Printf("%v: %v\n", board, err)
+		case minimal:
+			fmt.Printf("%v: minimal\n", board)
+		default:
+			fmt.Printf("%v: %v redundant hints at squares %v\n", board, len(redundant), redundant)
+		}
+	})
+}
+
+// forEachInputBoard reads boards from stdin in the format selected by -format
+// and calls fn with each board and the line it starts on. Invalid boards are
+// reported with their line and skipped.
+func forEachInputBoard(fn func(v sudoku.Values, line int)) {
+	format, err := sudoku.ParseBoardFormat(*formatFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	br := sudoku.NewBoardReader(os.Stdin)
+	br.Format = format
+	for {
+		p, err := br.Read()
+		if err == io.EOF {
+			return
+		}
+		var recordErr *sudoku.RecordError
+		if errors.As(err, &recordErr) {
+			log.Print(err)
 			continue
+		} else if err != nil {
+			log.Fatal(err)
 		}
-		boards = append(boards, board)
+		fn(p.Board, br.Line())
 	}
+}
 
-	if err := scanner.Err(); err != nil {
+// boardLine returns the board on a single line, with '.' for empty squares.
+func boardLine(values sudoku.Values) string {
+	text, err := values.MarshalText()
+	if err != nil {
 		log.Fatal(err)
 	}
-	return boards
+	return string(text)
 }
@@ -3,7 +3,6 @@ package main
 import (
 	"bytes"
 	"fmt"
-	"log"
 	"math/rand"
 	"syscall/js"
 	"time"
@@ -15,8 +14,9 @@ func main() {
 	rand.Seed(time.Now().UnixNano())
 	fmt.Println("go-sudoku wasm")
 
-	// Export the jsGenerateBoard function to JS.
+	// Export the jsGenerateBoard and jsLoadBoard functions to JS.
 	js.Global().Set("generateBoard", jsGenerateBoard)
+	js.Global().Set("loadBoard", jsLoadBoard)
 
 	// For the Go code to be usable from JS, the main function has to run forever.
 	<-make(chan bool)
@@ -24,28 +24,55 @@ func main() {
 
 // jsGenerateBoard wraps the functionality we need from this package, for use
 // in the web interface. It creates a function that takes two parameters:
-// an integer hint count, and a boolean "is symmetrical" flag. It returns
-// the SVG generated for the board as a string.
+// an integer hint count, and the name of the symmetry of hints (see
+// sudoku.ParseSymmetry). It returns the board as returned by boardResult.
 var jsGenerateBoard = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
 	if len(args) != 2 {
-		return fmt.Sprintf("got %v args, want 2", len(args))
+		return errorResult(fmt.Errorf("got %v args, want 2", len(args)))
 	}
 	hintCount := args[0].Int()
-	symmetrical := args[1].Bool()
+	symmetry, err := sudoku.ParseSymmetry(args[1].String())
+	if err != nil {
+		return errorResult(err)
+	}
+
+	return boardResult(sudoku.GenerateWithSymmetry(hintCount, symmetry))
+})
 
-	var board sudoku.Values
-	if symmetrical {
-		board = sudoku.GenerateSymmetrical(hintCount)
-	} else {
-		board = sudoku.Generate(hintCount)
+// jsLoadBoard creates a function that takes a puzzle ID (see sudoku.Decode),
+// and returns the board as returned by boardResult.
+var jsLoadBoard = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+	if len(args) != 1 {
+		return errorResult(fmt.Errorf("got %v args, want 1", len(args)))
 	}
+	board, err := sudoku.Decode(args[0].String())
+	if err != nil {
+		return errorResult(err)
+	}
+	return boardResult(board)
+})
 
+// boardResult returns an object with the SVG generated for the board as a
+// string in its "svg" field, and the board's ID in its "id" field.
+func boardResult(board sudoku.Values) interface{} {
 	d, err := sudoku.EvaluateDifficulty(board)
 	if err != nil {
-		log.Fatal(err)
+		return errorResult(err)
 	}
 
 	var buf bytes.Buffer
 	sudoku.DisplayAsSVG(&buf, board, d)
-	return buf.String()
-})
+	return map[string]interface{}{
+		"svg": buf.String(),
+		"id":  sudoku.Encode(board),
+	}
+}
+
+// errorResult returns an object like boardResult's, with the error message
+// instead of the SVG and an empty ID.
+func errorResult(err error) interface{} {
+	return map[string]interface{}{
+		"svg": err.Error(),
+		"id":  "",
+	}
+}
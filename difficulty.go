@@ -1,11 +1,215 @@
 package sudoku
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"strings"
 
 	"golang.org/x/exp/slices"
 )
 
+// DifficultyWeights holds the weights of the factors EvaluateDifficulty
+// combines into its final score, and the thresholds that map each factor to
+// its sub-score. The weights are normally non-negative and sum to 1, so that
+// the score stays in the 1.0-5.0 range of the sub-scores.
+type DifficultyWeights struct {
+	HintsBeforeElimination float64 `json:"hints_before_elimination"`
+	HintsAfterElimination  float64 `json:"hints_after_elimination"`
+	MinRowColHints         float64 `json:"min_row_col_hints"`
+	Searches               float64 `json:"searches"`
+
+	// Thresholds are the thresholds of the sub-scores; if zero,
+	// DefaultDifficultyThresholds are used.
+	Thresholds DifficultyThresholds `json:"thresholds"`
+}
+
+// DifficultyThresholds holds the thresholds that map each factor of
+// EvaluateDifficulty to its sub-score, from 1.0 to 5.0. The sub-score of a
+// number of hints is 1.0 plus the number of its thresholds that the number is
+// at most, so they're in decreasing order; the sub-score of the average number
+// of searches is 1.0 plus the number of its thresholds that the average
+// reaches, so they're in increasing order. The average reaches the first
+// threshold only if it exceeds it, and the others if it's at least equal to
+// them: with the default thresholds, an average of 1 search gets 1.0 and an
+// average of 3 searches gets 3.0.
+type DifficultyThresholds struct {
+	HintsBeforeElimination [4]int     `json:"hints_before_elimination"`
+	HintsAfterElimination  [4]int     `json:"hints_after_elimination"`
+	MinRowColHints         [4]int     `json:"min_row_col_hints"`
+	Searches               [4]float64 `json:"searches"`
+}
+
+// DefaultDifficultyThresholds are the thresholds used by EvaluateDifficulty.
+var DefaultDifficultyThresholds = DifficultyThresholds{
+	HintsBeforeElimination: [4]int{50, 35, 31, 27},
+	HintsAfterElimination:  [4]int{55, 42, 37, 33},
+	MinRowColHints:         [4]int{4, 3, 2, 0},
+	Searches:               [4]float64{1, 3, 10, 40},
+}
+
+// DefaultDifficultyWeights are the weights used by EvaluateDifficulty.
+var DefaultDifficultyWeights = DifficultyWeights{
+	HintsBeforeElimination: 0.3,
+	HintsAfterElimination:  0.5,
+	MinRowColHints:         0.05,
+	Searches:               0.15,
+	Thresholds:             DefaultDifficultyThresholds,
+}
+
+// LoadDifficultyWeights reads weights and thresholds in JSON format from r,
+// e.g.
+//
+//	{"hints_before_elimination": 0.3, "hints_after_elimination": 0.5,
+//	 "min_row_col_hints": 0.05, "searches": 0.15,
+//	 "thresholds": {"searches": [1, 3, 10, 40]}}
+//
+// Weights and thresholds missing from the input keep their values from
+// DefaultDifficultyWeights. It returns an error if any weight is negative, if
+// they're all zero, or if some thresholds are out of order.
+func LoadDifficultyWeights(r io.Reader) (DifficultyWeights, error) {
+	weights := DefaultDifficultyWeights
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&weights); err != nil {
+		return DifficultyWeights{}, fmt.Errorf("reading difficulty weights: %w", err)
+	}
+
+	all := []float64{weights.HintsBeforeElimination, weights.HintsAfterElimination, weights.MinRowColHints, weights.Searches}
+	var sum float64
+	for _, w := range all {
+		if w < 0 {
+			return DifficultyWeights{}, fmt.Errorf("negative difficulty weight %v", w)
+		}
+		sum += w
+	}
+	if sum == 0 {
+		return DifficultyWeights{}, fmt.Errorf("all difficulty weights are zero")
+	}
+	if err := weights.Thresholds.validate(); err != nil {
+		return DifficultyWeights{}, err
+	}
+	return weights, nil
+}
+
+// validate returns an error if the thresholds of some factor are out of order.
+func (t DifficultyThresholds) validate() error {
+	for _, f := range []struct {
+		name       string
+		thresholds [4]int
+	}{
+		{"hints_before_elimination", t.HintsBeforeElimination},
+		{"hints_after_elimination", t.HintsAfterElimination},
+		{"min_row_col_hints", t.MinRowColHints},
+	} {
+		for i := 1; i < len(f.thresholds); i++ {
+			if f.thresholds[i] > f.thresholds[i-1] {
+				return fmt.Errorf("%v thresholds %v aren't in decreasing order", f.name, f.thresholds)
+			}
+		}
+	}
+	for i := 1; i < len(t.Searches); i++ {
+		if t.Searches[i] < t.Searches[i-1] {
+			return fmt.Errorf("searches thresholds %v aren't in increasing order", t.Searches)
+		}
+	}
+	return nil
+}
+
+// SearchMode selects how EvaluateDifficultyReport measures the number of
+// searches a backtracking solver needs for a board.
+type SearchMode int
+
+const (
+	// SearchRandom averages randomized searches using the rand package's
+	// default source; the score may differ between runs.
+	SearchRandom SearchMode = iota
+
+	// SearchSeeded averages randomized searches using a source seeded with
+	// DifficultyOptions.Seed; the score is the same for the same seed.
+	SearchSeeded
+
+	// SearchExhaustive counts the searches needed to explore the whole search
+	// tree with a fixed digit order, proving the solution is unique. It's
+	// deterministic, but slower and usually higher than the randomized
+	// average. It requires a board with a single solution.
+	SearchExhaustive
+)
+
+// DefaultDifficultyIterations is the default number of randomized searches
+// averaged by EvaluateDifficultyReport.
+const DefaultDifficultyIterations = 10
+
+// DifficultyOptions configures EvaluateDifficultyReport.
+type DifficultyOptions struct {
+	// Weights are the weights of the factors of the score and the thresholds
+	// of their sub-scores; if zero, DefaultDifficultyWeights is used.
+	Weights DifficultyWeights
+
+	// Mode selects how searches are measured.
+	Mode SearchMode
+
+	// Iterations is the number of randomized searches averaged in
+	// SearchRandom and SearchSeeded modes; if zero,
+	// DefaultDifficultyIterations is used.
+	Iterations int
+
+	// Seed seeds the randomized searches in SearchSeeded mode.
+	Seed int64
+}
+
+// DifficultyReport is a breakdown of the difficulty score computed by
+// EvaluateDifficultyReport: the raw factors, the sub-score (from 1.0 to 5.0)
+// assigned to each, the weights used to combine them and the final score.
+type DifficultyReport struct {
+	HintsBeforeElimination int
+	HintsAfterElimination  int
+	MinRowColHints         int
+	AverageSearches        float64
+
+	// Iterations is the number of searches AverageSearches is averaged over,
+	// and SearchesStdDev is their sample standard deviation. In
+	// SearchExhaustive mode, Iterations is 1 and SearchesStdDev is 0.
+	Iterations     int
+	SearchesStdDev float64
+
+	HintsBeforeEliminationScore float64
+	HintsAfterEliminationScore  float64
+	MinRowColHintsScore         float64
+	SearchesScore               float64
+
+	Weights DifficultyWeights
+	Score   float64
+
+	// ScoreLow and ScoreHigh bound the score for the 95% confidence interval
+	// of AverageSearches; they're equal to Score when the searches were
+	// exhaustive or didn't vary.
+	ScoreLow  float64
+	ScoreHigh float64
+}
+
+// String implements the fmt.Stringer interface for DifficultyReport, as a
+// table showing how the score is computed.
+func (r DifficultyReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%-26s %8s %6s %7s\n", "factor", "value", "score", "weight")
+	row := func(name string, value string, score, weight float64) {
+		fmt.Fprintf(&sb, "%-26s %8s %6.2f %7.3f\n", name, value, score, weight)
+	}
+	row("hints before elimination", fmt.Sprint(r.HintsBeforeElimination), r.HintsBeforeEliminationScore, r.Weights.HintsBeforeElimination)
+	row("hints after elimination", fmt.Sprint(r.HintsAfterElimination), r.HintsAfterEliminationScore, r.Weights.HintsAfterElimination)
+	row("min row/col hints", fmt.Sprint(r.MinRowColHints), r.MinRowColHintsScore, r.Weights.MinRowColHints)
+	row("average searches", fmt.Sprintf("%.1f", r.AverageSearches), r.SearchesScore, r.Weights.Searches)
+	if r.ScoreLow != r.ScoreHigh {
+		fmt.Fprintf(&sb, "difficulty: %.2f (95%% CI %.2f-%.2f)\n", r.Score, r.ScoreLow, r.ScoreHigh)
+	} else {
+		fmt.Fprintf(&sb, "difficulty: %.2f\n", r.Score)
+	}
+	return sb.String()
+}
+
 // EvaluateDifficulty evaluates the difficulty of a Sudoku puzzle heuristically
 // and returns the score on a scale from 1.0 (easiest) to 5.0 hardest. It can
 // also return an error if the given board has contradictions, is unsolvable,
@@ -22,7 +226,33 @@ import (
 //
 // This approach was partially inspired by the paper "Sudoku Puzzles Generating:
 // from Easy to Evil" by Xiang-Sun ZHANG's research group.
+//
+// The weights of the factors and the thresholds of their sub-scores are
+// DefaultDifficultyWeights, and searches are
+// randomized, so the score may vary between runs. Use EvaluateDifficultyReport
+// for other weights, for deterministic scores or for a breakdown of the score.
 func EvaluateDifficulty(values Values) (float64, error) {
+	report, err := EvaluateDifficultyReport(values, DifficultyOptions{})
+	if err != nil {
+		return 0, err
+	}
+	return report.Score, nil
+}
+
+// EvaluateDifficultyReport evaluates the difficulty of a Sudoku puzzle like
+// EvaluateDifficulty, configured by opts, and returns a report with the factors
+// and sub-scores the final score is computed from. It's safe to call
+// concurrently (unless opts.Mode is SearchRandom and the rand package's
+// default source is being seeded concurrently).
+func EvaluateDifficultyReport(values Values, opts DifficultyOptions) (DifficultyReport, error) {
+	iterations := opts.Iterations
+	if iterations == 0 {
+		iterations = DefaultDifficultyIterations
+	}
+	if iterations < 0 {
+		return DifficultyReport{}, fmt.Errorf("got %v iterations, want a positive number", iterations)
+	}
+
 	hintsBeforeElimination := CountHints(values)
 
 	// Count the lower bound (minimal number) of hints in individual rows and
@@ -62,83 +292,151 @@ func EvaluateDifficulty(values Values) (float64, error) {
 	// Run elimination and count how many hints are on the board after it.
 	vcopy := slices.Clone(values)
 	if !EliminateAll(vcopy) {
-		return 0, fmt.Errorf("contradiction in board")
+		return DifficultyReport{}, fmt.Errorf("contradiction in board")
 	}
 	hintsAfterElimination := CountHints(vcopy)
 
-	// Run a number of randomized searches and count the average search count.
-	EnableStats = true
-	var totalSearches uint64 = 0
-	iterations := 10
-	for i := 0; i < iterations; i++ {
-		Stats.Reset()
-		_, solved := Solve(vcopy, SolveOptions{Randomize: true})
-		if !solved {
-			return 0, fmt.Errorf("cannot solve")
+	// Measure the number of searches needed to solve the board.
+	var searches []uint64
+	switch opts.Mode {
+	case SearchRandom, SearchSeeded:
+		solveOpts := SolveOptions{Randomize: true}
+		if opts.Mode == SearchSeeded {
+			solveOpts.Rand = rand.New(rand.NewSource(opts.Seed))
 		}
-		totalSearches += Stats.NumSearches
-	}
-	EnableStats = false
-	averageSearches := float64(totalSearches) / float64(iterations)
-
-	// Assign difficulty scores based on ranges in each category.
-	var hintsBeforeDifficulty float64
-	if hintsBeforeElimination > 50 {
-		hintsBeforeDifficulty = 1.0
-	} else if hintsBeforeElimination > 35 {
-		hintsBeforeDifficulty = 2.0
-	} else if hintsBeforeElimination > 31 {
-		hintsBeforeDifficulty = 3.0
-	} else if hintsBeforeElimination > 27 {
-		hintsBeforeDifficulty = 4.0
-	} else {
-		hintsBeforeDifficulty = 5.0
-	}
-
-	var hintsAfterDifficulty float64
-	if hintsAfterElimination > 55 {
-		hintsAfterDifficulty = 1.0
-	} else if hintsAfterElimination > 42 {
-		hintsAfterDifficulty = 2.0
-	} else if hintsAfterElimination > 37 {
-		hintsAfterDifficulty = 3.0
-	} else if hintsAfterElimination > 33 {
-		hintsAfterDifficulty = 4.0
-	} else {
-		hintsAfterDifficulty = 5.0
-	}
-
-	var minHintsDifficulty float64
-	if minHints >= 5 {
-		minHintsDifficulty = 1.0
-	} else if minHints == 4 {
-		minHintsDifficulty = 2.0
-	} else if minHints == 3 {
-		minHintsDifficulty = 3.0
-	} else if minHints >= 1 {
-		minHintsDifficulty = 4.0
-	} else {
-		minHintsDifficulty = 5.0
-	}
-
-	var searchDifficulty float64
-	if averageSearches <= 1.0 {
-		searchDifficulty = 1.0
-	} else if averageSearches < 3.0 {
-		searchDifficulty = 2.0
-	} else if averageSearches < 10.0 {
-		searchDifficulty = 3.0
-	} else if averageSearches < 40.0 {
-		searchDifficulty = 4.0
-	} else {
-		searchDifficulty = 5.0
+		for i := 0; i < iterations; i++ {
+			var count uint64
+			solveOpts.searches = &count
+			if _, solved := Solve(vcopy, solveOpts); !solved {
+				return DifficultyReport{}, fmt.Errorf("cannot solve")
+			}
+			searches = append(searches, count)
+		}
+	case SearchExhaustive:
+		count, solutions := countSearchTree(vcopy, 2)
+		switch {
+		case solutions == 0:
+			return DifficultyReport{}, fmt.Errorf("cannot solve")
+		case solutions > 1:
+			return DifficultyReport{}, ErrMultipleSolutions
+		}
+		searches = append(searches, count)
+	default:
+		return DifficultyReport{}, fmt.Errorf("unknown search mode %v", opts.Mode)
 	}
+	averageSearches, stddev := meanAndStdDev(searches)
+
+	r := DifficultyReport{
+		HintsBeforeElimination: hintsBeforeElimination,
+		HintsAfterElimination:  hintsAfterElimination,
+		MinRowColHints:         minHints,
+		AverageSearches:        averageSearches,
+		Iterations:             len(searches),
+		SearchesStdDev:         stddev,
+	}
+	return r.Rescore(opts.Weights), nil
+}
+
+// Rescore returns r with its sub-scores and score computed again from its
+// factors with the given weights and thresholds, without evaluating the board
+// again; if weights is zero, DefaultDifficultyWeights is used, and if its
+// thresholds are zero, DefaultDifficultyThresholds.
+func (r DifficultyReport) Rescore(weights DifficultyWeights) DifficultyReport {
+	if weights == (DifficultyWeights{}) {
+		weights = DefaultDifficultyWeights
+	}
+	if weights.Thresholds == (DifficultyThresholds{}) {
+		weights.Thresholds = DefaultDifficultyThresholds
+	}
+	t := weights.Thresholds
+	r.HintsBeforeEliminationScore = hintsScore(r.HintsBeforeElimination, t.HintsBeforeElimination)
+	r.HintsAfterEliminationScore = hintsScore(r.HintsAfterElimination, t.HintsAfterElimination)
+	r.MinRowColHintsScore = hintsScore(r.MinRowColHints, t.MinRowColHints)
+	r.SearchesScore = searchesScore(r.AverageSearches, t.Searches)
 
 	// Assign final difficulty with weights
-	difficulty := 0.5*hintsAfterDifficulty +
-		0.3*hintsBeforeDifficulty +
-		0.05*minHintsDifficulty +
-		0.15*searchDifficulty
+	r.Weights = weights
+	r.Score = weights.HintsAfterElimination*r.HintsAfterEliminationScore +
+		weights.HintsBeforeElimination*r.HintsBeforeEliminationScore +
+		weights.MinRowColHints*r.MinRowColHintsScore +
+		weights.Searches*r.SearchesScore
 
-	return difficulty, nil
+	// The other factors are exact, so the confidence interval of the score
+	// only depends on the searches.
+	margin := 0.0
+	if r.Iterations > 0 {
+		margin = 1.96 * r.SearchesStdDev / math.Sqrt(float64(r.Iterations))
+	}
+	scoreFor := func(averageSearches float64) float64 {
+		return r.Score + weights.Searches*(searchesScore(averageSearches, t.Searches)-r.SearchesScore)
+	}
+	r.ScoreLow = scoreFor(r.AverageSearches - margin)
+	r.ScoreHigh = scoreFor(r.AverageSearches + margin)
+	return r
+}
+
+// hintsScore returns the difficulty sub-score for a number of hints: 1.0 plus
+// the number of thresholds it's at most.
+func hintsScore(hints int, thresholds [4]int) float64 {
+	score := 1.0
+	for _, t := range thresholds {
+		if hints <= t {
+			score++
+		}
+	}
+	return score
+}
+
+// searchesScore returns the difficulty sub-score for the average number of
+// searches needed to solve a board: 1.0 plus the number of thresholds it
+// reaches, i.e. exceeds for the first one, and is at least for the others.
+func searchesScore(averageSearches float64, thresholds [4]float64) float64 {
+	score := 1.0
+	for i, t := range thresholds {
+		if averageSearches > t || (i > 0 && averageSearches == t) {
+			score++
+		}
+	}
+	return score
+}
+
+// meanAndStdDev returns the mean and the sample standard deviation of counts.
+func meanAndStdDev(counts []uint64) (float64, float64) {
+	var sum float64
+	for _, c := range counts {
+		sum += float64(c)
+	}
+	mean := sum / float64(len(counts))
+	if len(counts) < 2 {
+		return mean, 0
+	}
+
+	var sqdiffs float64
+	for _, c := range counts {
+		sqdiffs += (float64(c) - mean) * (float64(c) - mean)
+	}
+	return mean, math.Sqrt(sqdiffs / float64(len(counts)-1))
+}
+
+// countSearchTree explores the search tree of values like SolveAll, with a
+// fixed digit order, and returns the number of searches made and the number
+// of solutions found; it stops after finding max solutions.
+func countSearchTree(values Values, max int) (uint64, int) {
+	squareToTry := findSquareWithFewestCandidates(values)
+	if squareToTry == -1 {
+		return 0, 1
+	}
+
+	searches, solutions := uint64(1), 0
+	for d := uint16(1); d <= 9 && solutions < max; d++ {
+		if values[squareToTry].IsMember(d) {
+			vcopy := slices.Clone(values)
+			if assign(vcopy, squareToTry, d) {
+				s, n := countSearchTree(vcopy, max-solutions)
+				searches += s
+				solutions += n
+			}
+		}
+	}
+	return searches, solutions
 }
@@ -1,8 +1,10 @@
 package sudoku
 
 import (
+	"errors"
 	"log"
 	"math/rand"
+	"strings"
 	"testing"
 	"time"
 
@@ -68,3 +70,188 @@ func TestFilled(t *testing.T) {
 		t.Errorf("got d=%v; expect difficulty of filled board to be 1.0", d)
 	}
 }
+
+func TestEvaluateDifficultyReport(t *testing.T) {
+	v, err := ParseBoard(hardboard2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := EvaluateDifficultyReport(v, DifficultyOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.HintsBeforeElimination != CountHints(v) {
+		t.Errorf("got %v hints before elimination, want %v", r.HintsBeforeElimination, CountHints(v))
+	}
+	if r.HintsAfterElimination < r.HintsBeforeElimination {
+		t.Errorf("got %v hints after elimination, fewer than %v before", r.HintsAfterElimination, r.HintsBeforeElimination)
+	}
+	for _, score := range []float64{r.HintsBeforeEliminationScore, r.HintsAfterEliminationScore, r.MinRowColHintsScore, r.SearchesScore} {
+		if score < 1.0 || score > 5.0 {
+			t.Errorf("got sub-score %v, want 1.0-5.0", score)
+		}
+	}
+	if r.Weights != DefaultDifficultyWeights {
+		t.Errorf("got weights %v, want %v", r.Weights, DefaultDifficultyWeights)
+	}
+
+	// Only count the hints before elimination; the score is then determined
+	// regardless of the randomized search.
+	weights := DifficultyWeights{HintsBeforeElimination: 1}
+	r, err = EvaluateDifficultyReport(v, DifficultyOptions{Weights: weights})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Score != r.HintsBeforeEliminationScore {
+		t.Errorf("got score %v, want %v", r.Score, r.HintsBeforeEliminationScore)
+	}
+	if !strings.Contains(r.String(), "hints before elimination") {
+		t.Errorf("got report %q", r.String())
+	}
+}
+
+func TestLoadDifficultyWeights(t *testing.T) {
+	w, err := LoadDifficultyWeights(strings.NewReader(`{"searches": 0.5, "min_row_col_hints": 0}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DefaultDifficultyWeights
+	want.Searches = 0.5
+	want.MinRowColHints = 0
+	if w != want {
+		t.Errorf("got %v, want %v", w, want)
+	}
+
+	w, err = LoadDifficultyWeights(strings.NewReader(`{"thresholds": {"searches": [2, 4, 8, 16]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = DefaultDifficultyWeights
+	want.Thresholds.Searches = [4]float64{2, 4, 8, 16}
+	if w != want {
+		t.Errorf("got %v, want %v", w, want)
+	}
+
+	for _, bad := range []string{
+		`{"searches": -1}`,
+		`{"thresholds": {"searches": [2, 1, 8, 16]}}`,
+		`{"thresholds": {"min_row_col_hints": [1, 2, 3, 4]}}`,
+		`{"thresholds": {"guesses": [1, 2, 3, 4]}}`,
+		`{"searches": 0, "min_row_col_hints": 0, "hints_before_elimination": 0, "hints_after_elimination": 0}`,
+		`{"guesses": 0.5}`,
+		`{"searches": `,
+	} {
+		if _, err := LoadDifficultyWeights(strings.NewReader(bad)); err == nil {
+			t.Errorf("got no error for %q", bad)
+		}
+	}
+}
+
+func TestSearchesScore(t *testing.T) {
+	// The default thresholds give the scores of the original mapping: <=1 is
+	// 1, <3 is 2, <10 is 3, <40 is 4 and anything else 5.
+	for _, test := range []struct {
+		searches float64
+		want     float64
+	}{
+		{0.5, 1}, {1, 1}, {1.1, 2}, {2.9, 2}, {3, 3}, {9.9, 3}, {10, 4}, {39.9, 4}, {40, 5}, {100, 5},
+	} {
+		if got := searchesScore(test.searches, DefaultDifficultyThresholds.Searches); got != test.want {
+			t.Errorf("got score %v for %v searches, want %v", got, test.searches, test.want)
+		}
+	}
+}
+
+func TestRescore(t *testing.T) {
+	v, err := ParseBoard(hardboard1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := EvaluateDifficultyReport(v, DifficultyOptions{Mode: SearchSeeded, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Rescore(DifficultyWeights{}); got != r {
+		t.Errorf("got different report rescored with default weights:\n%v\n%v", got, r)
+	}
+
+	// Raising the thresholds of hints before elimination above the number of
+	// hints gives the highest sub-score.
+	weights := DifficultyWeights{HintsBeforeElimination: 1}
+	weights.Thresholds = DefaultDifficultyThresholds
+	weights.Thresholds.HintsBeforeElimination = [4]int{r.HintsBeforeElimination + 3, r.HintsBeforeElimination + 2, r.HintsBeforeElimination + 1, r.HintsBeforeElimination}
+	got := r.Rescore(weights)
+	if got.HintsBeforeEliminationScore != 5.0 || got.Score != 5.0 {
+		t.Errorf("got sub-score %v and score %v, want 5.0", got.HintsBeforeEliminationScore, got.Score)
+	}
+	if got.Weights != weights {
+		t.Errorf("got weights %v, want %v", got.Weights, weights)
+	}
+}
+
+func TestEvaluateDifficultyDeterministic(t *testing.T) {
+	v, err := ParseBoard(hardboard2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, opts := range []DifficultyOptions{
+		{Mode: SearchSeeded, Seed: 42},
+		{Mode: SearchSeeded, Seed: 42, Iterations: 30},
+		{Mode: SearchExhaustive},
+	} {
+		r1, err := EvaluateDifficultyReport(v, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r2, err := EvaluateDifficultyReport(v, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r1 != r2 {
+			t.Errorf("got different reports for %+v:\n%v\n%v", opts, r1, r2)
+		}
+		if r1.ScoreLow > r1.Score || r1.Score > r1.ScoreHigh {
+			t.Errorf("got score %v outside of confidence interval %v-%v", r1.Score, r1.ScoreLow, r1.ScoreHigh)
+		}
+	}
+
+	r, err := EvaluateDifficultyReport(v, DifficultyOptions{Mode: SearchSeeded, Iterations: 25})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Iterations != 25 || r.SearchesStdDev == 0 {
+		t.Errorf("got %v iterations with stddev %v", r.Iterations, r.SearchesStdDev)
+	}
+
+	r, err = EvaluateDifficultyReport(v, DifficultyOptions{Mode: SearchExhaustive})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Iterations != 1 || r.SearchesStdDev != 0 || r.ScoreLow != r.ScoreHigh {
+		t.Errorf("got exhaustive report %+v", r)
+	}
+
+	// Exhaustive search needs a unique solution.
+	empty := EmptyBoard()
+	if _, err := EvaluateDifficultyReport(empty, DifficultyOptions{Mode: SearchExhaustive}); !errors.Is(err, ErrMultipleSolutions) {
+		t.Errorf("got err %v, want ErrMultipleSolutions", err)
+	}
+}
+
+func TestEvaluateDifficultyKeepsStats(t *testing.T) {
+	v, err := ParseBoard(hardboard2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	WithStats(func() {
+		if _, err := EvaluateDifficulty(v); err != nil {
+			t.Fatal(err)
+		}
+		if !EnableStats || Stats.NumSearches != 0 {
+			t.Errorf("got EnableStats=%v, NumSearches=%v", EnableStats, Stats.NumSearches)
+		}
+	})
+}
@@ -0,0 +1,411 @@
+package sudoku
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// This file contains forcing chains and forcing nets: last-resort techniques
+// that make an assumption about a candidate, propagate its consequences with
+// the same assign/eliminate machinery that Solve uses, and draw conclusions
+// from the results:
+//
+//   - Digit forcing: if assuming a candidate is true (or false) leads to a
+//     contradiction, it must be false (or true). If both assuming it true and
+//     assuming it false lead to the same result, that result holds.
+//   - Cell forcing: if each candidate of a square leads to the same result,
+//     that result holds.
+//   - Unit forcing: if each possible position of a digit in a unit leads to
+//     the same result, that result holds.
+//
+// Forcing chains only follow a limited number of implications from the
+// assumption (forcingChainDepth), keeping explanations short. Forcing nets
+// follow implications up to LogicOptions.ForcingNetDepth, or without limit.
+
+// forcingChainDepth is the maximal number of implications followed from the
+// premise by forcing chains.
+const forcingChainDepth = 6
+
+// Implication is a node in the implication tree built while propagating an
+// assumption: a fact about a candidate that follows from its Parent. The root
+// of the tree (with a nil Parent) is the assumption itself.
+type Implication struct {
+	Candidate Candidate
+
+	// Holds is true if the fact is that Candidate's square holds its digit,
+	// and false if the fact is that the digit is eliminated from the square.
+	Holds bool
+
+	// Contradiction is true if this node marks a contradiction reached from
+	// Parent; Candidate and Holds are then the same as Parent's.
+	Contradiction bool
+
+	// Reason is a short explanation of why the fact follows from Parent, if
+	// it's not obvious (e.g. "hidden single in row 3").
+	Reason string
+
+	Parent *Implication
+
+	// Also lists other implications that this one depends on besides Parent,
+	// when it follows from several facts (e.g. a naked single that needs all
+	// the other candidates of its square to be eliminated). This makes the
+	// implications of a forcing net a graph rather than a tree.
+	Also []*Implication
+
+	depth int
+}
+
+// String implements the fmt.Stringer interface for Implication.
+func (im *Implication) String() string {
+	switch {
+	case im.Contradiction:
+		return fmt.Sprintf("contradiction (%s)", im.Reason)
+	case im.Holds:
+		return im.Candidate.String()
+	default:
+		return fmt.Sprintf("%s<>%d", squareName(im.Candidate.Square), im.Candidate.Digit)
+	}
+}
+
+// Path returns the chain of implications from the assumption to im,
+// inclusive.
+func (im *Implication) Path() []*Implication {
+	var path []*Implication
+	for n := im; n != nil; n = n.Parent {
+		path = append(path, n)
+	}
+	slices.Reverse(path)
+	return path
+}
+
+// Explain returns a human-readable explanation of how im follows from the
+// assumption, e.g. "r3c5=4 -> r3c6<>4 -> r4c6=2 (hidden single in row 4)".
+// Implications in Also are explained in brackets.
+func (im *Implication) Explain() string {
+	return im.explain(true)
+}
+
+func (im *Implication) explain(withAlso bool) string {
+	var parts []string
+	for _, n := range im.Path() {
+		s := n.String()
+		if n.Reason != "" && !n.Contradiction && n.Parent != nil {
+			s += " (" + n.Reason + ")"
+		}
+		if withAlso && len(n.Also) > 0 {
+			var also []string
+			for _, a := range n.Also {
+				also = append(also, a.explain(false))
+			}
+			s += " [and " + strings.Join(also, "; ") + "]"
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " -> ")
+}
+
+// factKey identifies an implied fact.
+type factKey struct {
+	sq    Index
+	digit uint16
+	holds bool
+}
+
+// tracer records the implications made by assignTraced and eliminateTraced.
+// All its methods can be called on a nil *tracer, in which case they do
+// nothing.
+type tracer struct {
+	// maxDepth is the maximal depth of implications to propagate; 0 means
+	// unlimited.
+	maxDepth int
+
+	facts map[factKey]*Implication
+	order []*Implication
+
+	// contradicted is the first contradiction reached, if any.
+	contradicted *Implication
+}
+
+func newTracer(maxDepth int) *tracer {
+	return &tracer{maxDepth: maxDepth, facts: make(map[factKey]*Implication)}
+}
+
+// record records a fact implied by cause, returning its node. If the fact was
+// already recorded, the existing node is returned.
+func (tr *tracer) record(sq Index, digit uint16, holds bool, cause *Implication, reason string) *Implication {
+	if tr == nil {
+		return nil
+	}
+	key := factKey{sq, digit, holds}
+	if im, ok := tr.facts[key]; ok {
+		return im
+	}
+	im := &Implication{Candidate: Candidate{sq, digit}, Holds: holds, Reason: reason, Parent: cause}
+	if cause != nil {
+		im.depth = cause.depth + 1
+	}
+	tr.facts[key] = im
+	tr.order = append(tr.order, im)
+	return im
+}
+
+func (tr *tracer) eliminated(sq Index, digit uint16, cause *Implication) *Implication {
+	return tr.record(sq, digit, false, cause, "")
+}
+
+// placed records that digit was placed in sq because of cause. If unit is
+// nil, this is a naked single, and the other eliminations from sq are added to
+// the implication's Also. Otherwise it's a hidden single in unit, and the
+// eliminations of digit from the other squares of unit are added.
+func (tr *tracer) placed(sq Index, digit uint16, cause *Implication, unit Unit) *Implication {
+	if tr == nil {
+		return nil
+	}
+	if unit == nil {
+		im := tr.record(sq, digit, true, cause, "naked single")
+		if im.Parent == cause {
+			for d := uint16(1); d <= 9; d++ {
+				if d != digit {
+					im.Also = tr.addAlso(im.Also, factKey{sq, d, false}, cause)
+				}
+			}
+		}
+		return im
+	}
+
+	im := tr.record(sq, digit, true, cause, "hidden single in "+unitNameOf(unit))
+	if im.Parent == cause {
+		for _, other := range unit {
+			if other != sq {
+				im.Also = tr.addAlso(im.Also, factKey{other, digit, false}, cause)
+			}
+		}
+	}
+	return im
+}
+
+// addAlso appends the implication recorded for key to also, unless it wasn't
+// recorded (the fact was known before the assumption) or it's cause itself.
+func (tr *tracer) addAlso(also []*Implication, key factKey, cause *Implication) []*Implication {
+	if im, ok := tr.facts[key]; ok && im != cause {
+		also = append(also, im)
+	}
+	return also
+}
+
+// contradiction records a contradiction reached from cause. If sq is not -1,
+// the contradiction is that sq has no candidates left, and its other
+// eliminations are added to the Also of the contradiction.
+func (tr *tracer) contradiction(cause *Implication, sq Index, reason string) {
+	if tr == nil || tr.contradicted != nil {
+		return
+	}
+	tr.contradicted = &Implication{Reason: reason, Parent: cause, Contradiction: true}
+	if cause != nil {
+		tr.contradicted.Candidate = cause.Candidate
+		tr.contradicted.Holds = cause.Holds
+		tr.contradicted.depth = cause.depth + 1
+	}
+	if sq >= 0 {
+		for d := uint16(1); d <= 9; d++ {
+			tr.contradicted.Also = tr.addAlso(tr.contradicted.Also, factKey{sq, d, false}, cause)
+		}
+	}
+}
+
+// depthReached reports whether implications shouldn't be propagated further
+// from im.
+func (tr *tracer) depthReached(im *Implication) bool {
+	return tr != nil && tr.maxDepth > 0 && im.depth >= tr.maxDepth
+}
+
+// branch is the result of propagating a single assumption.
+type branch struct {
+	tr *tracer
+	ok bool
+}
+
+// propagateAssumption assumes that candidate c is true (if holds) or false,
+// and propagates the consequences on a copy of values, following at most
+// depth implications (0 means unlimited).
+func propagateAssumption(values Values, c Candidate, holds bool, depth int) branch {
+	vcopy := slices.Clone(values)
+	tr := newTracer(depth)
+	premise := tr.record(c.Square, c.Digit, holds, nil, "assumption")
+
+	var ok bool
+	if holds {
+		ok = assignTraced(vcopy, c.Square, c.Digit, tr, premise)
+	} else {
+		ok = eliminateTraced(vcopy, c.Square, c.Digit, tr, premise)
+	}
+	return branch{tr: tr, ok: ok}
+}
+
+// forcingTechniques are the techniques reported by one flavor (chains or nets)
+// of forcing strategies.
+type forcingTechniques struct {
+	digit, cell, unit Technique
+}
+
+var chainTechniques = forcingTechniques{DigitForcingChain, CellForcingChain, UnitForcingChain}
+var netTechniques = forcingTechniques{DigitForcingNet, CellForcingNet, UnitForcingNet}
+
+// findForcingChains is the strategy for forcing chains.
+func findForcingChains(values Values, opts LogicOptions) *Step {
+	return findForcing(values, forcingChainDepth, chainTechniques, opts.MaxRating)
+}
+
+// findForcingNets is the strategy for forcing nets.
+func findForcingNets(values Values, opts LogicOptions) *Step {
+	return findForcing(values, opts.ForcingNetDepth, netTechniques, opts.MaxRating)
+}
+
+// findForcing looks for digit, cell and unit forcing with assumptions
+// propagated up to depth implications deep. Kinds of forcing rated above
+// maxRating (unless it's 0) aren't looked for, so they don't hide the allowed
+// ones.
+func findForcing(values Values, depth int, techs forcingTechniques, maxRating float64) *Step {
+	allowed := func(t Technique) bool {
+		return maxRating == 0 || t.Rating() <= maxRating
+	}
+
+	// Assume each candidate in turn; a contradiction eliminates it.
+	on := make(map[Candidate]branch)
+	for sq, d := range values {
+		if d.Size() < 2 {
+			continue
+		}
+		for _, digit := range digitsOf(d) {
+			c := Candidate{sq, digit}
+			b := propagateAssumption(values, c, true, depth)
+			if !b.ok {
+				step := &Step{
+					Technique:    techs.digit,
+					Eliminations: []Candidate{c},
+					Cells:        []Index{sq},
+					Digits:       SingleDigitSet(digit),
+					Implications: []*Implication{b.tr.contradicted},
+				}
+				return describe(step, "if %s", b.tr.contradicted.Explain())
+			}
+			on[c] = b
+		}
+	}
+
+	// Cell forcing, trying squares with fewer candidates first.
+	for size := 2; size <= 9 && allowed(techs.cell); size++ {
+		for sq, d := range values {
+			if d.Size() != size {
+				continue
+			}
+			var branches []branch
+			for _, digit := range digitsOf(d) {
+				branches = append(branches, on[Candidate{sq, digit}])
+			}
+			if step := forcingStep(values, techs.cell, branches); step != nil {
+				step.Cells = []Index{sq}
+				step.Digits = d
+				return step
+			}
+		}
+	}
+
+	// Unit forcing.
+	for _, unit := range unitlist {
+		if !allowed(techs.unit) {
+			break
+		}
+		for digit := uint16(1); digit <= 9; digit++ {
+			var branches []branch
+			var cells []Index
+			for _, sq := range unit {
+				if values[sq].IsMember(digit) {
+					if values[sq].Size() == 1 {
+						branches = nil
+						break
+					}
+					branches = append(branches, on[Candidate{sq, digit}])
+					cells = append(cells, sq)
+				}
+			}
+			if len(branches) < 2 {
+				continue
+			}
+			if step := forcingStep(values, techs.unit, branches); step != nil {
+				step.Cells = cells
+				step.Digits = SingleDigitSet(digit)
+				return step
+			}
+		}
+	}
+
+	// Digit forcing with both assumptions: the candidate is true or false.
+	for sq, d := range values {
+		if d.Size() < 2 {
+			continue
+		}
+		for _, digit := range digitsOf(d) {
+			c := Candidate{sq, digit}
+			off := propagateAssumption(values, c, false, depth)
+			if !off.ok {
+				step := &Step{
+					Technique:    techs.digit,
+					Placements:   []Candidate{c},
+					Cells:        []Index{sq},
+					Digits:       SingleDigitSet(digit),
+					Implications: []*Implication{off.tr.contradicted},
+				}
+				return describe(step, "if %s", off.tr.contradicted.Explain())
+			}
+			if step := forcingStep(values, techs.digit, []branch{on[c], off}); step != nil {
+				step.Cells = []Index{sq}
+				step.Digits = SingleDigitSet(digit)
+				return step
+			}
+		}
+	}
+	return nil
+}
+
+// forcingStep finds the conclusions common to all branches that are new on
+// the board, and returns a step for them; it returns nil if there are none.
+// One of the branches must be true, so these conclusions hold.
+func forcingStep(values Values, t Technique, branches []branch) *Step {
+	step := &Step{Technique: t}
+	for _, im := range branches[0].tr.order {
+		c := im.Candidate
+		if values[c.Square].Size() < 2 || !values[c.Square].IsMember(c.Digit) {
+			continue
+		}
+		key := factKey{c.Square, c.Digit, im.Holds}
+		var ends []*Implication
+		for _, b := range branches {
+			if end, ok := b.tr.facts[key]; ok {
+				ends = append(ends, end)
+			}
+		}
+		if len(ends) != len(branches) {
+			continue
+		}
+		if im.Holds {
+			step.Placements = append(step.Placements, c)
+		} else {
+			step.Eliminations = append(step.Eliminations, c)
+		}
+		if step.Implications == nil {
+			step.Implications = ends
+		}
+	}
+	if step.Implications == nil {
+		return nil
+	}
+
+	var explanations []string
+	for _, end := range step.Implications {
+		explanations = append(explanations, "if "+end.Explain())
+	}
+	return describe(step, "%s", strings.Join(explanations, "; "))
+}
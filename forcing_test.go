@@ -0,0 +1,140 @@
+package sudoku
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestTracedPropagation(t *testing.T) {
+	// Propagation with a tracer should reach the same board as without one.
+	v, err := ParseBoard(hardboard1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for sq, d := range v {
+		if d.Size() < 2 {
+			continue
+		}
+		for _, digit := range digitsOf(d) {
+			plain := slices.Clone(v)
+			plainOk := assign(plain, sq, digit)
+
+			traced := slices.Clone(v)
+			tr := newTracer(0)
+			premise := tr.record(sq, digit, true, nil, "assumption")
+			tracedOk := assignTraced(traced, sq, digit, tr, premise)
+
+			if plainOk != tracedOk || (plainOk && !slices.Equal(plain, traced)) {
+				t.Fatalf("traced propagation of %s differs", Candidate{sq, digit})
+			}
+			if !tracedOk && tr.contradicted == nil {
+				t.Errorf("got no contradiction recorded for %s", Candidate{sq, digit})
+			}
+
+			// Every recorded elimination must be reflected on the board, and every
+			// implication must lead back to the premise.
+			for _, im := range tr.order {
+				if tracedOk && !im.Holds && traced[im.Candidate.Square].IsMember(im.Candidate.Digit) {
+					t.Errorf("recorded %v, but the candidate is still on the board", im)
+				}
+				if path := im.Path(); path[0] != premise {
+					t.Errorf("implication %v doesn't lead back to the premise", im)
+				}
+			}
+		}
+	}
+}
+
+func TestImplicationExplain(t *testing.T) {
+	// Three squares in row 1 with candidates 12, 12 and 123: assuming r1c3=1
+	// empties r1c1 and r1c2 of 1, leaving 2 in both.
+	v := EmptyBoard()
+	v[0] = Digits(0).Add(1).Add(2)
+	v[1] = Digits(0).Add(1).Add(2)
+	v[2] = Digits(0).Add(1).Add(2).Add(3)
+
+	b := propagateAssumption(v, Candidate{2, 1}, true, 0)
+	if b.ok || b.tr.contradicted == nil {
+		t.Fatalf("expect contradiction")
+	}
+	explanation := b.tr.contradicted.Explain()
+	if !strings.HasPrefix(explanation, "r1c3=1 -> ") || !strings.Contains(explanation, "contradiction") {
+		t.Errorf("got explanation %q", explanation)
+	}
+
+	step := findForcingChains(v, LogicOptions{})
+	if step == nil || step.Technique != DigitForcingChain {
+		t.Fatalf("got step %v, want digit forcing chain", step)
+	}
+	if !slices.Equal(step.Eliminations, []Candidate{{2, 1}}) || len(step.Implications) != 1 || !step.Implications[0].Contradiction {
+		t.Errorf("got implications %v", step.Implications)
+	}
+	if !strings.HasPrefix(step.Description, "Digit Forcing Chain: if r1c3=1 -> ") {
+		t.Errorf("got description %q", step.Description)
+	}
+}
+
+func TestForcingSteps(t *testing.T) {
+	boards := readInputBoards(t, "inputs/norvig-hard.txt")
+	if testing.Short() {
+		boards = boards[:10]
+	}
+
+	for _, board := range boards {
+		v, err := ParseBoard(board, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		solution, _ := Solve(v)
+
+		_, steps, _ := SolveLogically(v, LogicOptions{ForcingNetDepth: 12})
+		checkStepsAgainstSolution(t, steps, solution)
+
+		for _, step := range steps {
+			if step.Technique < DigitForcingChain {
+				continue
+			}
+			if len(step.Implications) == 0 {
+				t.Errorf("got no implications for %q", step.Description)
+			}
+			for _, im := range step.Implications {
+				path := im.Path()
+				if path[0].Parent != nil || path[0].Reason != "assumption" {
+					t.Errorf("got path not starting with an assumption: %v", path)
+				}
+				maxDepth := forcingChainDepth
+				if step.Technique >= DigitForcingNet {
+					maxDepth = 12
+				}
+				if len(path) > maxDepth+2 {
+					t.Errorf("got path of length %v for %v", len(path), step.Technique)
+				}
+			}
+		}
+	}
+}
+
+func TestForcingMaxRating(t *testing.T) {
+	// Solving this board needs a unit forcing chain at some point, where a
+	// digit forcing chain also applies: with MaxRating limited to digit
+	// forcing chains, the solver finds the latter.
+	board := readInputBoards(t, "inputs/norvig-hard.txt")[6]
+	v, err := ParseBoard(board, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, steps, _ := SolveLogically(v)
+	for _, step := range steps {
+		if step.Technique == CellForcingChain || step.Technique == UnitForcingChain {
+			got, ok := NextStep(v, LogicOptions{MaxRating: DigitForcingChain.Rating()})
+			if !ok || got.Technique != DigitForcingChain {
+				t.Errorf("got step %v (%v) with MaxRating %v, want a digit forcing chain", got.Technique, ok, DigitForcingChain.Rating())
+			}
+			return
+		}
+		ApplyStep(v, step)
+	}
+	t.Fatal("got no cell or unit forcing chain")
+}
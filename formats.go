@@ -0,0 +1,196 @@
+package sudoku
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// This file contains readers and writers for common Sudoku file formats:
+//
+//   - SadMan Software's .sdk: a single puzzle, as 9 lines of 9 squares with .
+//     for empty squares, optionally preceded by metadata lines (#A author, #D
+//     description, #C comment, #B date, #S source, #L level, #U URL) and a
+//     [Puzzle] header. Other sections, like [State], are ignored.
+//   - .sdm: a collection of puzzles, one per line as 81 digits with 0 for empty
+//     squares.
+//   - Simple Sudoku's .ss: a single puzzle, as 9 lines of 9 squares with . for
+//     empty squares, | between boxes and lines of - between bands.
+
+// sdkMetadata maps the metadata letters of .sdk files to fields of Puzzle, in
+// the order they're written.
+var sdkMetadata = []struct {
+	letter byte
+	field  func(p *Puzzle) *string
+}{
+	{'A', func(p *Puzzle) *string { return &p.Author }},
+	{'D', func(p *Puzzle) *string { return &p.Title }},
+	{'C', func(p *Puzzle) *string { return &p.Comment }},
+	{'B', func(p *Puzzle) *string { return &p.Date }},
+	{'S', func(p *Puzzle) *string { return &p.Source }},
+	{'L', func(p *Puzzle) *string { return &p.Level }},
+	{'U', func(p *Puzzle) *string { return &p.URL }},
+}
+
+// ReadSDK reads a puzzle in SadMan Software's .sdk format from r. The title
+// of the puzzle is taken from the description; several comment lines are
+// joined with newlines.
+func ReadSDK(r io.Reader) (Puzzle, error) {
+	var p Puzzle
+	var rows []string
+	section := "puzzle"
+
+	scanner := bufio.NewScanner(r)
+	lineno := 0
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case len(line) == 0:
+		case line[0] == '#':
+			if len(line) < 2 {
+				continue
+			}
+			value := strings.TrimSpace(line[2:])
+			for _, m := range sdkMetadata {
+				if m.letter == line[1] {
+					if field := m.field(&p); len(*field) > 0 && m.letter == 'C' {
+						*field += "\n" + value
+					} else {
+						*field = value
+					}
+				}
+			}
+		case line[0] == '[':
+			section = strings.ToLower(strings.Trim(line, "[]"))
+		case section == "puzzle":
+			if len(rows) == 9 {
+				return Puzzle{}, fmt.Errorf("line %v: more than 9 rows in puzzle", lineno)
+			}
+			rows = append(rows, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Puzzle{}, err
+	}
+
+	board, err := ParseBoard(strings.Join(rows, ""), false)
+	if err != nil {
+		return Puzzle{}, err
+	}
+	p.Board = board
+	return p, nil
+}
+
+// WriteSDK writes p to w in SadMan Software's .sdk format, with a metadata
+// line for each of its non-empty metadata fields that the format supports.
+func WriteSDK(w io.Writer, p Puzzle) error {
+	if len(p.Board) != 81 {
+		return fmt.Errorf("got board of %v squares, want 81", len(p.Board))
+	}
+	bw := bufio.NewWriter(w)
+	for _, m := range sdkMetadata {
+		if value := *m.field(&p); len(value) > 0 {
+			for _, line := range strings.Split(value, "\n") {
+				fmt.Fprintf(bw, "#%c%s\n", m.letter, line)
+			}
+		}
+	}
+	fmt.Fprintln(bw, "[Puzzle]")
+	for row := 0; row < 9; row++ {
+		for col := 0; col < 9; col++ {
+			bw.WriteByte(squareChar(p.Board[row*9+col], '.'))
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// ReadSDM reads a collection of puzzles in the .sdm format from r. Empty lines
+// are skipped.
+func ReadSDM(r io.Reader) ([]Puzzle, error) {
+	var puzzles []Puzzle
+	scanner := bufio.NewScanner(r)
+	lineno := 0
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+		board, err := ParseBoard(line, false)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %w", lineno, err)
+		}
+		puzzles = append(puzzles, Puzzle{Board: board})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return puzzles, nil
+}
+
+// WriteSDM writes puzzles to w in the .sdm format. The format has no
+// metadata, so only the boards are written. Nothing is written if any of the
+// boards is invalid.
+func WriteSDM(w io.Writer, puzzles []Puzzle) error {
+	for i, p := range puzzles {
+		if len(p.Board) != 81 {
+			return fmt.Errorf("puzzle %v: got board of %v squares, want 81", i+1, len(p.Board))
+		}
+	}
+	bw := bufio.NewWriter(w)
+	for _, p := range puzzles {
+		for _, d := range p.Board {
+			bw.WriteByte(squareChar(d, '0'))
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// ReadSS reads a puzzle in Simple Sudoku's .ss format from r.
+func ReadSS(r io.Reader) (Puzzle, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Puzzle{}, err
+	}
+	// ParseBoard ignores the separators between boxes and bands.
+	board, err := ParseBoard(string(data), false)
+	if err != nil {
+		return Puzzle{}, err
+	}
+	return Puzzle{Board: board}, nil
+}
+
+// WriteSS writes p to w in Simple Sudoku's .ss format. The format has no
+// metadata, so only the board is written.
+func WriteSS(w io.Writer, p Puzzle) error {
+	if len(p.Board) != 81 {
+		return fmt.Errorf("got board of %v squares, want 81", len(p.Board))
+	}
+	bw := bufio.NewWriter(w)
+	for row := 0; row < 9; row++ {
+		if row == 3 || row == 6 {
+			fmt.Fprintln(bw, "-----------")
+		}
+		for col := 0; col < 9; col++ {
+			if col == 3 || col == 6 {
+				bw.WriteByte('|')
+			}
+			bw.WriteByte(squareChar(p.Board[row*9+col], '.'))
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// squareChar returns the digit of a square as a character, or blank if the
+// square doesn't have a single digit.
+func squareChar(d Digits, blank byte) byte {
+	if d.Size() == 1 {
+		return byte('0' + d.SingleMemberDigit())
+	}
+	return blank
+}
@@ -0,0 +1,157 @@
+package sudoku
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestReadWriteSDK(t *testing.T) {
+	input := `#AArto Inkala
+#DEverest
+#CSaid to be the world's hardest sudoku.
+#Cwith a second comment line
+#LExtreme
+[Puzzle]
+8........
+..36.....
+.7..9.2..
+.5...7...
+....457..
+...1...3.
+..1....68
+..85...1.
+.9....4..
+[State]
+812753649
+943682175
+675491283
+154237896
+369845721
+287169534
+521974368
+438526917
+796318452
+`
+	p, err := ReadSDK(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Puzzle{
+		Author:  "Arto Inkala",
+		Title:   "Everest",
+		Comment: "Said to be the world's hardest sudoku.\nwith a second comment line",
+		Level:   "Extreme",
+	}
+	if p.Author != want.Author || p.Title != want.Title || p.Comment != want.Comment || p.Level != want.Level {
+		t.Errorf("got metadata %+v, want %+v", p, want)
+	}
+	if CountHints(p.Board) != 21 || p.Board[0] != SingleDigitSet(8) {
+		t.Errorf("got board:\n%v", DisplayAsInput(p.Board))
+	}
+
+	// Round trip.
+	var buf bytes.Buffer
+	if err := WriteSDK(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	p2, err := ReadSDK(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(p2.Board, p.Board) || p2.Comment != p.Comment || p2.Title != p.Title {
+		t.Errorf("got different puzzle after round trip: %+v", p2)
+	}
+
+	// Without metadata or headers.
+	bare := strings.Join(strings.Split(input, "\n")[6:15], "\n")
+	p3, err := ReadSDK(strings.NewReader(bare))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(p3.Board, p.Board) {
+		t.Errorf("got different board without headers:\n%v", DisplayAsInput(p3.Board))
+	}
+
+	if _, err := ReadSDK(strings.NewReader(bare + "\n123456789\n")); err == nil {
+		t.Errorf("got no error for 10 rows")
+	}
+}
+
+func TestReadWriteSDM(t *testing.T) {
+	input := easyboard1 + "\n\n" + strings.ReplaceAll(hardboard1, ".", "0") + "\n"
+	puzzles, err := ReadSDM(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(puzzles) != 2 {
+		t.Fatalf("got %v puzzles, want 2", len(puzzles))
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSDM(&buf, puzzles); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), strings.Replace(input, "\n\n", "\n", 1); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	if _, err := ReadSDM(strings.NewReader(easyboard1 + "\n12345\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("got err %v, want error on line 2", err)
+	}
+}
+
+func TestReadWriteSS(t *testing.T) {
+	input := `..3|.2.|6..
+9..|3.5|..1
+..1|8.6|4..
+-----------
+..8|1.2|9..
+7..|...|..8
+..6|7.8|2..
+-----------
+..2|6.9|5..
+8..|2.3|..9
+..5|.1.|3..
+`
+	p, err := ReadSS(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ParseBoard(easyboard1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(p.Board, want) {
+		t.Errorf("got board:\n%v", DisplayAsInput(p.Board))
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSS(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != input {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), input)
+	}
+}
+
+func TestWriteInvalidBoard(t *testing.T) {
+	for _, board := range []Values{nil, EmptyBoard()[:80]} {
+		p := Puzzle{Board: board}
+		var buf bytes.Buffer
+		if err := WriteSDK(&buf, p); err == nil {
+			t.Errorf("WriteSDK: got no error for board of %v squares", len(board))
+		}
+		if err := WriteSDM(&buf, []Puzzle{{Board: EmptyBoard()}, p}); err == nil {
+			t.Errorf("WriteSDM: got no error for board of %v squares", len(board))
+		}
+		if err := WriteSS(&buf, p); err == nil {
+			t.Errorf("WriteSS: got no error for board of %v squares", len(board))
+		}
+		if buf.Len() != 0 {
+			t.Errorf("got output %q for invalid board", buf.String())
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package sudoku
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// This file implements importing and exporting puzzles in the format of
+// f-puzzles (https://f-puzzles.com), which SudokuPad also reads: JSON
+// compressed with LZ-string's compressToBase64. The supported constraints map
+// to Constraint types as follows:
+//
+//   - killercage: "killer", with the cage's squares and its sum as Value.
+//   - thermometer: "thermo", one per line, with the squares from the bulb.
+//   - arrow: "arrow", with the squares of the circle followed by the squares of
+//     the arrow; Value is the number of squares of the circle.
+//   - diagonal-: "diagonal", from the top left to the bottom right.
+//   - diagonal+: "antidiagonal", from the top right to the bottom left.
+//   - difference: "kropki-white", the white dot between two squares, with its
+//     difference as Value if it isn't 1.
+//   - ratio: "kropki-black", the black dot between two squares, with its ratio
+//     as Value if it isn't 2.
+
+// fpuzzlesIgnored lists the keys of f-puzzles puzzles that don't affect the
+// solution, so they're ignored when importing.
+var fpuzzlesIgnored = map[string]bool{
+	"size": true, "grid": true, "title": true, "author": true, "ruleset": true,
+	"solution": true, "highlightConflicts": true, "disabledlogic": true,
+	"truecandidatesoptions": true, "text": true, "line": true, "rectangle": true,
+	"circle": true, "cage": true,
+}
+
+// fpuzzlesCell is a square of the grid of an f-puzzles puzzle.
+type fpuzzlesCell struct {
+	Value             int   `json:"value,omitempty"`
+	Given             bool  `json:"given,omitempty"`
+	Region            *int  `json:"region,omitempty"`
+	GivenPencilMarks  []int `json:"givenPencilMarks,omitempty"`
+	CenterPencilMarks []int `json:"centerPencilMarks,omitempty"`
+	CornerPencilMarks []int `json:"cornerPencilMarks,omitempty"`
+}
+
+// fpuzzlesValue is the value of an f-puzzles constraint, which may be written
+// as a string or as a number.
+type fpuzzlesValue string
+
+func (v *fpuzzlesValue) UnmarshalJSON(data []byte) error {
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err == nil {
+		*v = fpuzzlesValue(n)
+		return nil
+	}
+	return json.Unmarshal(data, (*string)(v))
+}
+
+type fpuzzlesCells struct {
+	Cells []string      `json:"cells"`
+	Value fpuzzlesValue `json:"value,omitempty"`
+}
+
+type fpuzzlesLines struct {
+	Lines [][]string `json:"lines"`
+}
+
+type fpuzzlesArrow struct {
+	Cells []string   `json:"cells"`
+	Lines [][]string `json:"lines"`
+}
+
+// fpuzzlesPuzzle is an f-puzzles puzzle, with the constraints this package
+// supports.
+type fpuzzlesPuzzle struct {
+	Size          int              `json:"size"`
+	Title         string           `json:"title,omitempty"`
+	Author        string           `json:"author,omitempty"`
+	Ruleset       string           `json:"ruleset,omitempty"`
+	Grid          [][]fpuzzlesCell `json:"grid"`
+	DiagonalPlus  bool             `json:"diagonal+,omitempty"`
+	DiagonalMinus bool             `json:"diagonal-,omitempty"`
+	KillerCage    []fpuzzlesCells  `json:"killercage,omitempty"`
+	Thermometer   []fpuzzlesLines  `json:"thermometer,omitempty"`
+	Arrow         []fpuzzlesArrow  `json:"arrow,omitempty"`
+	Difference    []fpuzzlesCells  `json:"difference,omitempty"`
+	Ratio         []fpuzzlesCells  `json:"ratio,omitempty"`
+}
+
+// ImportFPuzzles imports a puzzle from f-puzzles. s is the compressed puzzle,
+// or a link to it on f-puzzles (with a load= parameter) or SudokuPad (with
+// the fpuzzles prefix). The givens of the puzzle become the board, other
+// digits in the grid become entries, and the title, author and rules become
+// Title, Author and Comment. Pencil marks and cosmetic elements are ignored.
+//
+// An error is returned for puzzles that aren't 9x9, and for constraints that
+// aren't supported, since the puzzle can't be solved without them.
+func ImportFPuzzles(s string) (Puzzle, error) {
+	data, err := lzDecompressFromBase64(fpuzzlesPayload(s))
+	if err != nil {
+		return Puzzle{}, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(data), &fields); err != nil {
+		return Puzzle{}, fmt.Errorf("invalid f-puzzles JSON: %w", err)
+	}
+	var fp fpuzzlesPuzzle
+	if err := json.Unmarshal([]byte(data), &fp); err != nil {
+		return Puzzle{}, fmt.Errorf("invalid f-puzzles JSON: %w", err)
+	}
+	if fp.Size != 9 || len(fp.Grid) != 9 {
+		return Puzzle{}, fmt.Errorf("got f-puzzles size %v, only 9 is supported", fp.Size)
+	}
+
+	// Report every unsupported constraint, in a stable order.
+	supported := map[string]bool{
+		"diagonal+": true, "diagonal-": true, "killercage": true,
+		"thermometer": true, "arrow": true, "difference": true, "ratio": true,
+	}
+	var unsupported []string
+	for key, value := range fields {
+		if !fpuzzlesIgnored[key] && !supported[key] && !fpuzzlesEmpty(value) {
+			unsupported = append(unsupported, key)
+		}
+	}
+
+	p := Puzzle{
+		Board:   EmptyBoard(),
+		Title:   fp.Title,
+		Author:  fp.Author,
+		Comment: fp.Ruleset,
+	}
+	for row, cells := range fp.Grid {
+		if len(cells) != 9 {
+			return Puzzle{}, fmt.Errorf("got %v squares in f-puzzles row %v, want 9", len(cells), row+1)
+		}
+		for col, cell := range cells {
+			if cell.Region != nil {
+				unsupported = append(unsupported, "region")
+			}
+			if len(cell.GivenPencilMarks) > 0 {
+				unsupported = append(unsupported, "givenPencilMarks")
+			}
+			if cell.Value == 0 {
+				continue
+			}
+			if cell.Value < 1 || cell.Value > 9 {
+				return Puzzle{}, fmt.Errorf("got digit %v in f-puzzles square R%vC%v", cell.Value, row+1, col+1)
+			}
+			if cell.Given {
+				p.Board[row*9+col] = SingleDigitSet(uint16(cell.Value))
+			} else {
+				if p.Entries == nil {
+					p.Entries = EmptyBoard()
+				}
+				p.Entries[row*9+col] = SingleDigitSet(uint16(cell.Value))
+			}
+		}
+	}
+	if len(unsupported) > 0 {
+		slices.Sort(unsupported)
+		unsupported = slices.Compact(unsupported)
+		return Puzzle{}, fmt.Errorf("unsupported f-puzzles constraints: %v", strings.Join(unsupported, ", "))
+	}
+
+	if fp.DiagonalMinus {
+		p.Constraints = append(p.Constraints, diagonalConstraint("diagonal"))
+	}
+	if fp.DiagonalPlus {
+		p.Constraints = append(p.Constraints, diagonalConstraint("antidiagonal"))
+	}
+	add := func(typ string, cells []string, value string) error {
+		squares, err := fpuzzlesSquares(cells)
+		if err != nil {
+			return err
+		}
+		p.Constraints = append(p.Constraints, Constraint{Type: typ, Squares: squares, Value: value})
+		return nil
+	}
+	for _, c := range fp.KillerCage {
+		if err := add("killer", c.Cells, string(c.Value)); err != nil {
+			return Puzzle{}, err
+		}
+	}
+	for _, c := range fp.Thermometer {
+		for _, line := range c.Lines {
+			if err := add("thermo", line, ""); err != nil {
+				return Puzzle{}, err
+			}
+		}
+	}
+	for _, c := range fp.Arrow {
+		for _, line := range c.Lines {
+			if len(line) < 2 {
+				return Puzzle{}, fmt.Errorf("got f-puzzles arrow of length %v", len(line))
+			}
+			// The line starts in one of the squares of the circle; put that
+			// square last in the circle so the arrow reads on from it.
+			var circle []string
+			for _, cell := range c.Cells {
+				if cell != line[0] {
+					circle = append(circle, cell)
+				}
+			}
+			circle = append(circle, line[0])
+			if err := add("arrow", append(circle, line[1:]...), strconv.Itoa(len(circle))); err != nil {
+				return Puzzle{}, err
+			}
+		}
+	}
+	for _, c := range fp.Difference {
+		value := string(c.Value)
+		if value == "1" {
+			value = ""
+		}
+		if err := add("kropki-white", c.Cells, value); err != nil {
+			return Puzzle{}, err
+		}
+	}
+	for _, c := range fp.Ratio {
+		value := string(c.Value)
+		if value == "2" {
+			value = ""
+		}
+		if err := add("kropki-black", c.Cells, value); err != nil {
+			return Puzzle{}, err
+		}
+	}
+	return p, nil
+}
+
+// ExportFPuzzles exports p to the compressed format of f-puzzles. Entries are
+// exported as digits that aren't given. An error is returned for constraints
+// that f-puzzles doesn't support.
+func ExportFPuzzles(p Puzzle) (string, error) {
+	if len(p.Board) != 81 {
+		return "", fmt.Errorf("got board of %v squares, want 81", len(p.Board))
+	}
+	fp := fpuzzlesPuzzle{
+		Size:    9,
+		Title:   p.Title,
+		Author:  p.Author,
+		Ruleset: p.Comment,
+		Grid:    make([][]fpuzzlesCell, 9),
+	}
+	for row := range fp.Grid {
+		fp.Grid[row] = make([]fpuzzlesCell, 9)
+		for col := range fp.Grid[row] {
+			sq := row*9 + col
+			if d := p.Board[sq]; d.Size() == 1 {
+				fp.Grid[row][col] = fpuzzlesCell{Value: int(d.SingleMemberDigit()), Given: true}
+			} else if len(p.Entries) == 81 && p.Entries[sq].Size() == 1 {
+				fp.Grid[row][col] = fpuzzlesCell{Value: int(p.Entries[sq].SingleMemberDigit())}
+			}
+		}
+	}
+
+	for _, c := range p.Constraints {
+		cells, err := fpuzzlesCellNames(c.Squares)
+		if err != nil {
+			return "", err
+		}
+		switch c.Type {
+		case "diagonal":
+			fp.DiagonalMinus = true
+		case "antidiagonal":
+			fp.DiagonalPlus = true
+		case "killer":
+			fp.KillerCage = append(fp.KillerCage, fpuzzlesCells{Cells: cells, Value: fpuzzlesValue(c.Value)})
+		case "thermo":
+			fp.Thermometer = append(fp.Thermometer, fpuzzlesLines{Lines: [][]string{cells}})
+		case "arrow":
+			n, err := strconv.Atoi(c.Value)
+			if err != nil || n < 1 || n >= len(cells) {
+				return "", fmt.Errorf("got arrow constraint with circle of %q squares", c.Value)
+			}
+			fp.Arrow = append(fp.Arrow, fpuzzlesArrow{Cells: cells[:n], Lines: [][]string{cells[n-1:]}})
+		case "kropki-white":
+			fp.Difference = append(fp.Difference, fpuzzlesCells{Cells: cells, Value: fpuzzlesValue(c.Value)})
+		case "kropki-black":
+			fp.Ratio = append(fp.Ratio, fpuzzlesCells{Cells: cells, Value: fpuzzlesValue(c.Value)})
+		default:
+			return "", fmt.Errorf("constraint %q isn't supported by f-puzzles", c.Type)
+		}
+	}
+
+	data, err := json.Marshal(fp)
+	if err != nil {
+		return "", err
+	}
+	return lzCompressToBase64(string(data)), nil
+}
+
+// fpuzzlesPayload extracts the compressed puzzle from s, which may be a link
+// to f-puzzles or SudokuPad.
+func fpuzzlesPayload(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, "load="); i >= 0 {
+		s = s[i+len("load="):]
+		if j := strings.IndexAny(s, "&#"); j >= 0 {
+			s = s[:j]
+		}
+	} else if i := strings.Index(s, "fpuzzles"); i >= 0 {
+		s = s[i+len("fpuzzles"):]
+	}
+	// Links may have the payload escaped; unlike query unescaping, path
+	// unescaping keeps + as is.
+	if unescaped, err := url.PathUnescape(s); err == nil {
+		s = unescaped
+	}
+	return strings.ReplaceAll(s, " ", "+")
+}
+
+// fpuzzlesEmpty reports whether value is an empty or false JSON value, for
+// constraints that are present but not used.
+func fpuzzlesEmpty(value json.RawMessage) bool {
+	switch strings.TrimSpace(string(value)) {
+	case "", "null", "false", "[]", "{}", `""`:
+		return true
+	}
+	return false
+}
+
+// fpuzzlesSquares converts names of squares like "R1C2" to indices.
+func fpuzzlesSquares(cells []string) ([]Index, error) {
+	squares := make([]Index, 0, len(cells))
+	for _, cell := range cells {
+		var row, col int
+		if n, err := fmt.Sscanf(strings.ToUpper(cell), "R%dC%d", &row, &col); n != 2 || err != nil ||
+			row < 1 || row > 9 || col < 1 || col > 9 {
+			return nil, fmt.Errorf("invalid f-puzzles square %q", cell)
+		}
+		squares = append(squares, Index((row-1)*9+col-1))
+	}
+	return squares, nil
+}
+
+// fpuzzlesCellNames converts indices of squares to names like "R1C2".
+func fpuzzlesCellNames(squares []Index) ([]string, error) {
+	cells := make([]string, 0, len(squares))
+	for _, sq := range squares {
+		if sq < 0 || sq >= 81 {
+			return nil, fmt.Errorf("invalid square %v", sq)
+		}
+		cells = append(cells, fmt.Sprintf("R%vC%v", sq/9+1, sq%9+1))
+	}
+	return cells, nil
+}
+
+// diagonalConstraint returns a constraint on the main diagonal, or with
+// typ "antidiagonal" on the other diagonal.
+func diagonalConstraint(typ string) Constraint {
+	c := Constraint{Type: typ}
+	for i := 0; i < 9; i++ {
+		if typ == "diagonal" {
+			c.Squares = append(c.Squares, Index(i*9+i))
+		} else {
+			c.Squares = append(c.Squares, Index(i*9+8-i))
+		}
+	}
+	return c
+}
@@ -0,0 +1,141 @@
+package sudoku
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestLZString(t *testing.T) {
+	// The outputs of LZ-string's compressToBase64, which f-puzzles uses; its
+	// links famously start like the first one. The end marker of the last two
+	// ends on a character boundary, and LZ-string still writes one more.
+	for _, test := range []struct {
+		s, want string
+	}{
+		{`{"size":9,"grid":[[{},{}]]}`, "N4IgzglgXgpiBcBOANCA5gJwgEwQbT2AF9liBdMooA=="},
+		{"Hello, world", "BIUwNmD2A0AEDukBOYAmQ==="},
+		{"ünïcödé ☃ 𝄞", "D8Ow9wxgbwJglwAkMBkDAsG4PF2g"},
+		{"23", "EwZiA==="},
+		{"{},", "N4XwNEA="},
+	} {
+		if got := lzCompressToBase64(test.s); got != test.want {
+			t.Errorf("got %v for %q, want %v", got, test.s, test.want)
+		}
+	}
+
+	for _, s := range []string{"a", "Hello, world", strings.Repeat("abcab", 100), "ünïcödé ☃ 𝄞"} {
+		got, err := lzDecompressFromBase64(lzCompressToBase64(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != s {
+			t.Errorf("got %q after round trip, want %q", got, s)
+		}
+	}
+
+	if _, err := lzDecompressFromBase64("N4Ig!"); err == nil {
+		t.Errorf("got no error for invalid character")
+	}
+}
+
+func TestImportFPuzzles(t *testing.T) {
+	data := `{"size":9,"title":"Test","author":"Me","ruleset":"Normal rules.",` +
+		`"grid":[[{"value":4,"given":true},{},{},{},{},{},{},{},{}],` +
+		`[{},{"value":5},{},{},{},{},{},{},{}],` +
+		strings.Repeat(`[{},{},{},{},{},{},{},{},{}],`, 6) +
+		`[{},{},{},{},{},{},{},{},{"value":9,"given":true,"centerPencilMarks":[1,2]}]],` +
+		`"diagonal-":true,"diagonal+":false,` +
+		`"killercage":[{"cells":["R1C2","R1C3"],"value":"10"}],` +
+		`"thermometer":[{"lines":[["R2C1","R3C1","R4C1"]]}],` +
+		`"arrow":[{"cells":["R5C5","R5C6"],"lines":[["R5C5","R6C5","R7C5"]]}],` +
+		`"difference":[{"cells":["R9C1","R9C2"]}],` +
+		`"ratio":[{"cells":["R9C3","R9C4"],"value":3}],` +
+		`"text":[{"cells":["R1C1"],"value":"hi"}],"antiknight":false,"extraregion":[]}`
+
+	p, err := ImportFPuzzles(lzCompressToBase64(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Title != "Test" || p.Author != "Me" || p.Comment != "Normal rules." {
+		t.Errorf("got metadata %+v", p)
+	}
+	if CountHints(p.Board) != 2 || p.Board[0] != SingleDigitSet(4) || p.Board[80] != SingleDigitSet(9) {
+		t.Errorf("got board:\n%v", DisplayAsInput(p.Board))
+	}
+	if p.Entries[10] != SingleDigitSet(5) || CountHints(p.Entries) != 1 {
+		t.Errorf("got entries:\n%v", DisplayAsInput(p.Entries))
+	}
+
+	want := []Constraint{
+		{Type: "diagonal", Squares: []Index{0, 10, 20, 30, 40, 50, 60, 70, 80}},
+		{Type: "killer", Squares: []Index{1, 2}, Value: "10"},
+		{Type: "thermo", Squares: []Index{9, 18, 27}},
+		{Type: "arrow", Squares: []Index{41, 40, 49, 58}, Value: "2"},
+		{Type: "kropki-white", Squares: []Index{72, 73}},
+		{Type: "kropki-black", Squares: []Index{74, 75}, Value: "3"},
+	}
+	if !reflect.DeepEqual(p.Constraints, want) {
+		t.Errorf("got constraints %+v, want %+v", p.Constraints, want)
+	}
+
+	// Round trip, also through a link.
+	exported, err := ExportFPuzzles(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{exported, "https://f-puzzles.com/?load=" + exported, "https://sudokupad.app/fpuzzles" + exported} {
+		p2, err := ImportFPuzzles(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(p2, p) {
+			t.Errorf("got different puzzle after round trip:\n%+v\n%+v", p2, p)
+		}
+	}
+}
+
+func TestImportFPuzzlesErrors(t *testing.T) {
+	grid := `"grid":[` + strings.Repeat(`[{},{},{},{},{},{},{},{},{}],`, 8) + `[{},{},{},{},{},{},{},{},{}]]`
+	for _, tt := range []struct {
+		json string
+		want string
+	}{
+		{`{"size":6,"grid":[]}`, "size 6"},
+		{`{"size":9,` + grid + `,"antiknight":true,"littlekillersum":[{"cells":["R1C1"]}]}`, "antiknight, littlekillersum"},
+		{`{"size":9,` + strings.Replace(grid, "{}", `{"region":0}`, 1) + `}`, "region"},
+		{`{"size":9,` + grid + `,"killercage":[{"cells":["R10C1"]}]}`, "R10C1"},
+		{`not JSON`, "invalid"},
+	} {
+		_, err := ImportFPuzzles(lzCompressToBase64(tt.json))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("got err %v for %v, want it to mention %q", err, tt.json, tt.want)
+		}
+	}
+}
+
+func TestExportFPuzzles(t *testing.T) {
+	board, err := ParseBoard(hardboard1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Puzzle{Board: board, Constraints: []Constraint{{Type: "antidiagonal", Squares: diagonalConstraint("antidiagonal").Squares}}}
+	exported, err := ExportFPuzzles(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, err := ImportFPuzzles(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(p2.Board, board) || !reflect.DeepEqual(p2.Constraints, p.Constraints) {
+		t.Errorf("got different puzzle after round trip: %+v", p2)
+	}
+
+	p.Constraints = []Constraint{{Type: "sandwich", Squares: []Index{0}}}
+	if _, err := ExportFPuzzles(p); err == nil || !strings.Contains(err.Error(), "sandwich") {
+		t.Errorf("got err %v, want unsupported constraint", err)
+	}
+}
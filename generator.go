@@ -1,8 +1,14 @@
 package sudoku
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"math/rand"
+	"time"
+
+	"golang.org/x/exp/slices"
 )
 
 // Generate generates a random Sudoku board that has a single solution, with
@@ -14,24 +20,51 @@ import (
 // though higher hint counts generally correlate with easier boards. It's
 // recommended to generate a large number of boards using this function and
 // evaluate their difficulty separately using EvaluateDifficulty.
+// With a hintCount of 0, every hint that can be removed is removed, so the
+// board is minimal (see IsMinimal).
 // Notes:
 //  * Make sure the default rand source is seeded if you really want to get
 //    random boards.
 //  * This function may take a while to run when given a low hintCount.
 func Generate(hintCount int) Values {
+	return GenerateWithSymmetry(hintCount, SymmetryNone)
+}
+
+// GenerateSymmetrical is similar to Generate, but it generates symmetrical
+// boards with 180-degree rotational symmetry.
+// Because of this additional constraint, it may have more trouble generating
+// boards with a small hintCount than Generate, so you'll have to run it more
+// times in a loop to find a good low-hint-count board.
+func GenerateSymmetrical(hintCount int) Values {
+	return GenerateWithSymmetry(hintCount, SymmetryRotational180)
+}
+
+// GenerateWithSymmetry is similar to Generate, but it generates boards with
+// the given symmetry of hints. The more squares the symmetry maps to each
+// other, the more trouble it has generating boards with a small hintCount;
+// boards may also end up with a few hints less than hintCount, since hints are
+// removed a whole orbit (see Symmetry.Orbits) at a time.
+func GenerateWithSymmetry(hintCount int, symmetry Symmetry) Values {
 	empty := EmptyBoard()
 	board, solved := Solve(empty, SolveOptions{Randomize: true})
 	if !solved || !IsSolved(board) {
 		log.Fatal("unable to generate solved board from empty")
 	}
 
-	removalOrder := rand.Perm(81)
+	// Instead of picking a random square out of all 81, pick a random orbit of
+	// squares under the symmetry and attempt to remove all of its squares.
+	orbits := symmetry.Orbits()
+	removalOrder := rand.Perm(len(orbits))
 	count := 81
 
-	for _, sq := range removalOrder {
-		savedDigit := board[sq]
-		// Try to remove the number from square sq.
-		board[sq] = FullDigitsSet()
+	for _, i := range removalOrder {
+		orbit := orbits[i]
+		saved := make([]Digits, len(orbit))
+		for j, sq := range orbit {
+			// Try to remove the number from square sq.
+			saved[j] = board[sq]
+			board[sq] = FullDigitsSet()
+		}
 
 		solutions := SolveAll(board, 2)
 		switch len(solutions) {
@@ -40,68 +73,404 @@ func Generate(hintCount int) Values {
 			// never result in an unsolvable board.
 			log.Fatal("got a board without solutions")
 		case 1:
-			count--
+			count -= len(orbit)
 			if count <= hintCount {
 				return board
 			}
 		default:
-			// The board has multiple solutions with this square emptied, so put it
-			// back and try again with the next square.
-			board[sq] = savedDigit
+			// The board has multiple solutions with this orbit emptied, so put it
+			// back and try again with the next orbit.
+			for j, sq := range orbit {
+				board[sq] = saved[j]
+			}
 		}
 	}
 
 	return board
 }
 
-// GenerateSymmetrical is similar to Generate, but it generates symmetrical
-// boards with 180-degree rotational symmetry.
-// Because of this additional constraint, it may have more trouble generating
-// boards with a small hintCount than Generate, so you'll have to run it more
-// times in a loop to find a good low-hint-count board.
-func GenerateSymmetrical(hintCount int) Values {
-	empty := EmptyBoard()
-	board, solved := Solve(empty, SolveOptions{Randomize: true})
-	if !solved || !IsSolved(board) {
-		log.Fatal("unable to generate solved board from empty")
+// IsMinimal reports whether the puzzle in values is minimal: whether removing
+// any of its clues (squares with a single candidate) leaves it with more than
+// one solution. It also returns the redundant clues, in increasing order:
+// clues that can each be removed on its own without losing uniqueness. It
+// returns ErrNoSolution if the puzzle has no solution, and ErrMultipleSolutions
+// if it has more than one. values is not modified.
+func IsMinimal(values Values) (bool, []Index, error) {
+	if len(values) != 81 {
+		return false, nil, fmt.Errorf("got board with %v squares, want 81", len(values))
+	}
+	hasSingleSolution := func(v Values) bool {
+		vcopy := slices.Clone(v)
+		return EliminateAll(vcopy) && len(SolveAll(vcopy, 2)) == 1
+	}
+	vcopy := slices.Clone(values)
+	if !EliminateAll(vcopy) {
+		return false, nil, ErrNoSolution
+	}
+	switch len(SolveAll(vcopy, 2)) {
+	case 0:
+		return false, nil, ErrNoSolution
+	case 1:
+	default:
+		return false, nil, ErrMultipleSolutions
 	}
 
-	// This function works just like Generate, but instead of picking a random
-	// square out of all 81, it picks a random square from the first half of the
-	// board and then attempts to remove both this square and its reflection.
-	removalOrder := rand.Perm(41)
-	count := 81
+	var redundant []Index
+	vcopy = slices.Clone(values)
+	for sq, d := range values {
+		if d.Size() != 1 {
+			continue
+		}
+		vcopy[sq] = FullDigitsSet()
+		if hasSingleSolution(vcopy) {
+			redundant = append(redundant, sq)
+		}
+		vcopy[sq] = d
+	}
+	return len(redundant) == 0, redundant, nil
+}
 
-	for _, sq := range removalOrder {
-		// Find sq's reflection; note that in the middle row reflectSq could equal
-		// sq - we take this into account when counting how many hints remain on
-		// the board.
-		reflectSq := 80 - sq
+// ErrTimeout is returned when generation doesn't finish in the requested time
+// or number of tries.
+var ErrTimeout = errors.New("timed out")
 
-		savedDigit := board[sq]
-		savedReflect := board[reflectSq]
+// GenerateOptions configures GenerateWithDifficulty.
+type GenerateOptions struct {
+	// Symmetry is the symmetry of hints on generated boards.
+	Symmetry Symmetry
 
-		board[sq] = FullDigitsSet()
-		board[reflectSq] = FullDigitsSet()
+	// MinHints is the minimal number of hints to leave on the board.
+	MinHints int
 
-		solutions := SolveAll(board, 2)
-		switch len(solutions) {
-		case 0:
-			log.Fatal("got a board without solutions")
-		case 1:
-			// We may have removed just one or two hints.
-			count--
-			if sq != reflectSq {
-				count--
+	// MaxHints, if not zero, is the number of hints to stop at: hints are
+	// removed until the board meets the target with at most MaxHints hints.
+	// If zero, hints are removed as long as the board keeps meeting the
+	// target.
+	MaxHints int
+
+	// Minimal requests minimal boards (see IsMinimal): hints are removed until
+	// every remaining hint is needed for the solution to be unique, while the
+	// board still meets the target. Combining it with MinHints or with a
+	// Symmetry makes generation much slower, or impossible.
+	Minimal bool
+
+	// Timeout limits the generation time; zero means no limit.
+	Timeout time.Duration
+
+	// MaxTries limits the number of candidate boards rated, like Timeout but
+	// independently of the speed of the machine; zero means no limit.
+	MaxTries int
+
+	// Difficulty configures the difficulty evaluation of candidate boards. A
+	// seeded mode makes the ratings (and, with Rand, the generated boards)
+	// reproducible.
+	Difficulty DifficultyOptions
+
+	// Rand is the source of randomness for generation. If nil, a source seeded
+	// from the rand package's default source is used.
+	Rand *rand.Rand
+
+	// ctx, if not nil, cancels generation when it's done.
+	ctx context.Context
+}
+
+// GenerateResult is the result of GenerateWithDifficulty and
+// GenerateWithTechniques.
+type GenerateResult struct {
+	// Board is the generated board, with a single solution.
+	Board Values
+
+	// Difficulty is the rating of Board: as computed by
+	// EvaluateDifficultyReport for GenerateWithDifficulty, and the technique
+	// rating for GenerateWithTechniques.
+	Difficulty float64
+
+	// Techniques is the technique rating of Board, including the steps of its
+	// logical solution; it's only set by GenerateWithTechniques.
+	Techniques TechniqueRating
+
+	// Tried is the number of candidate boards that were rated.
+	Tried int
+}
+
+// generateCandidatesPerStep is the number of removals generateSteered rates
+// before choosing one.
+const generateCandidatesPerStep = 8
+
+// When generateSteered gets stuck, it puts back up to generateBacktrackDepth of
+// the last removals and tries again, up to generateBacktracks times for each
+// solved board.
+const (
+	generateBacktracks     = 20
+	generateBacktrackDepth = 5
+)
+
+// generateEval is the evaluation of a candidate board by generateSteered.
+type generateEval struct {
+	// ok is false if the board overshoots the target, so its hints must not
+	// be removed.
+	ok bool
+
+	// done is true if the board meets the target.
+	done bool
+
+	// score orders boards that are ok; higher scores are closer to the target.
+	score float64
+
+	difficulty float64
+	techniques TechniqueRating
+}
+
+// GenerateWithDifficulty generates a random Sudoku board that has a single
+// solution and a difficulty (as evaluated by EvaluateDifficultyReport) in the
+// inclusive range [min, max].
+//
+// Starting from a random solved board, it repeatedly rates a few candidate
+// removals of hints and applies the one with the highest rating that doesn't
+// exceed max; removals that overshoot max are undone. Once the board is in the
+// range, it keeps removing hints while the board stays in it, until
+// opts.MaxHints is reached or no removal does, so at least one hint is always
+// removed. If the board gets stuck below min, it starts over with a new solved
+// board. It returns an error if
+// opts.Timeout passes or opts.MaxTries boards are tried before a board is
+// found (with Tried set), or if the range is invalid.
+func GenerateWithDifficulty(min, max float64, opts GenerateOptions) (GenerateResult, error) {
+	if min > max {
+		return GenerateResult{}, fmt.Errorf("invalid difficulty range [%v, %v]", min, max)
+	}
+	return generateSteered(opts, func(board Values) (generateEval, error) {
+		r, err := EvaluateDifficultyReport(board, opts.Difficulty)
+		if err != nil {
+			return generateEval{}, err
+		}
+		return generateEval{
+			ok:         r.Score <= max,
+			done:       r.Score >= min && r.Score <= max,
+			score:      r.Score,
+			difficulty: r.Score,
+		}, nil
+	})
+}
+
+// TechniqueRequirement describes the techniques the logical solution of a
+// board generated by GenerateWithTechniques must use.
+type TechniqueRequirement struct {
+	// Required lists techniques that must each be used at least once.
+	Required []Technique
+
+	// MaxRating is the rating of the hardest technique that may be used (see
+	// Technique.Rating); if zero, the highest rating of Required is used.
+	MaxRating float64
+}
+
+// GenerateWithTechniques generates a random Sudoku board that has a single
+// solution, where the logical solver uses each of req.Required at least once
+// and nothing rated harder than req.MaxRating. Since the solver always applies
+// the easiest technique available, a technique it uses is needed at that
+// point of the solution. The result includes the technique rating of the
+// board, with its steps.
+//
+// Generation works like GenerateWithDifficulty, preferring removals that use
+// more of the required techniques, then ones with higher ratings; removals
+// that need harder techniques are undone. opts.Difficulty is ignored. It
+// returns an error if opts.Timeout passes or opts.MaxTries boards are tried
+// before a board is found, or if the requirement can't be met.
+func GenerateWithTechniques(req TechniqueRequirement, opts GenerateOptions) (GenerateResult, error) {
+	maxRating := req.MaxRating
+	if maxRating == 0 {
+		for _, t := range req.Required {
+			if t.Rating() > maxRating {
+				maxRating = t.Rating()
 			}
-			if count <= hintCount {
-				return board
+		}
+	}
+	for _, t := range req.Required {
+		if t.Rating() == 0 {
+			return GenerateResult{}, fmt.Errorf("unknown technique %v", t)
+		}
+		if t.Rating() > maxRating {
+			return GenerateResult{}, fmt.Errorf("required %v is rated above %v", t, maxRating)
+		}
+	}
+	if maxRating == 0 {
+		maxRating = unsolvedRating
+	}
+
+	return generateSteered(opts, func(board Values) (generateEval, error) {
+		// Boards are only evaluated after checking they have a single
+		// solution, so uniqueness techniques are safe.
+		r := rateTechniques(board, LogicOptions{AssumeUnique: true, MaxRating: maxRating})
+		if !r.Solved {
+			return generateEval{}, nil
+		}
+		used := 0
+		for _, t := range req.Required {
+			if r.Histogram[t] > 0 {
+				used++
 			}
-		default:
-			board[sq] = savedDigit
-			board[reflectSq] = savedReflect
 		}
+		return generateEval{
+			ok:         true,
+			done:       used == len(req.Required),
+			score:      float64(used)*unsolvedRating + r.Rating,
+			difficulty: r.Rating,
+			techniques: r,
+		}, nil
+	})
+}
+
+// generateSteered generates a board by removing hints from a random solved
+// board, steered by evaluate. At each step it evaluates a few candidate
+// removals that keep the solution unique, and applies the ok one with the
+// highest score. Once the board is done, only removals that keep it done are
+// applied, until it has at most opts.MaxHints hints or no such removal is found
+// (and it's minimal, if opts.Minimal is set); a solved board is never returned.
+// If no candidate is ok, it backtracks a few removals, and eventually starts
+// over with a new solved board.
+func generateSteered(opts GenerateOptions, evaluate func(board Values) (generateEval, error)) (GenerateResult, error) {
+	rng := opts.Rand
+	if rng == nil {
+		rng = rand.New(rand.NewSource(rand.Int63()))
 	}
+	start := time.Now()
 
-	return board
+	var result GenerateResult
+	rate := func(board Values) (generateEval, error) {
+		if opts.MaxTries > 0 && result.Tried >= opts.MaxTries {
+			return generateEval{}, ErrTimeout
+		}
+		result.Tried++
+		return evaluate(board)
+	}
+
+	// Hints are removed a whole orbit under the symmetry at a time.
+	groups := opts.Symmetry.Orbits()
+
+	for {
+		board, solved := Solve(EmptyBoard(), SolveOptions{Randomize: true, Rand: rng})
+		if !solved || !IsSolved(board) {
+			log.Fatal("unable to generate solved board from empty")
+		}
+		solution := slices.Clone(board)
+		eval, err := rate(board)
+		if err != nil {
+			return result, err
+		}
+		hints := 81
+		remaining := slices.Clone(groups)
+
+		// The removals applied so far, and the evaluation of the board before
+		// each of them, for backtracking.
+		var removed [][]Index
+		var evals []generateEval
+		backtracks := 0
+
+		accept := func() (GenerateResult, error) {
+			result.Board = board
+			result.Difficulty = eval.difficulty
+			result.Techniques = eval.techniques
+			return result, nil
+		}
+
+		for {
+			if eval.done && opts.MaxHints > 0 && hints <= opts.MaxHints && (!opts.Minimal || isMinimalBoard(board)) {
+				return accept()
+			}
+
+			// Evaluate candidate removals, keeping the ok one with the highest
+			// score.
+			rng.Shuffle(len(remaining), func(i, j int) {
+				remaining[i], remaining[j] = remaining[j], remaining[i]
+			})
+			best := -1
+			var bestEval generateEval
+			rated := 0
+			for i := 0; i < len(remaining) && rated < generateCandidatesPerStep; i++ {
+				if opts.Timeout > 0 && time.Since(start) > opts.Timeout {
+					return result, ErrTimeout
+				}
+				if opts.ctx != nil && opts.ctx.Err() != nil {
+					return result, opts.ctx.Err()
+				}
+
+				group := remaining[i]
+				if hints-len(group) < opts.MinHints {
+					continue
+				}
+				candidate := slices.Clone(board)
+				for _, sq := range group {
+					candidate[sq] = FullDigitsSet()
+				}
+				eliminated := slices.Clone(candidate)
+				if !EliminateAll(eliminated) || len(SolveAll(eliminated, 2)) != 1 {
+					// Removing more hints can't make the solution unique again,
+					// so this group can't be removed until we backtrack.
+					remaining = slices.Delete(remaining, i, i+1)
+					i--
+					continue
+				}
+
+				rated++
+				e, err := rate(candidate)
+				if err != nil {
+					return result, err
+				}
+				// Once the target is met, only removals that keep meeting it
+				// are applied (for minimal boards).
+				if e.ok && (e.done || !eval.done) && (best == -1 || e.score > bestEval.score) {
+					best, bestEval = i, e
+				}
+			}
+
+			if best == -1 {
+				// No removal keeps the board done, so it's as far as removals
+				// go.
+				if eval.done && hints < 81 && opts.MaxHints == 0 && (!opts.Minimal || isMinimalBoard(board)) {
+					return accept()
+				}
+
+				// Every removal is impossible or overshoots. Boards close to
+				// this one are the most likely to meet the target, so put back
+				// a few of the last removals and try again, a limited number of
+				// times before starting over.
+				if backtracks >= generateBacktracks || len(removed) == 0 {
+					break
+				}
+				backtracks++
+				n := 1 + rng.Intn(min(len(removed), generateBacktrackDepth))
+				for _, group := range removed[len(removed)-n:] {
+					for _, sq := range group {
+						board[sq] = solution[sq]
+					}
+					hints += len(group)
+				}
+				eval = evals[len(evals)-n]
+				removed = removed[:len(removed)-n]
+				evals = evals[:len(evals)-n]
+
+				remaining = remaining[:0]
+				for _, group := range groups {
+					if board[group[0]].Size() == 1 {
+						remaining = append(remaining, group)
+					}
+				}
+				continue
+			}
+			removed = append(removed, remaining[best])
+			evals = append(evals, eval)
+			for _, sq := range remaining[best] {
+				board[sq] = FullDigitsSet()
+			}
+			hints -= len(remaining[best])
+			remaining = slices.Delete(remaining, best, best+1)
+			eval = bestEval
+		}
+	}
+}
+
+// isMinimalBoard reports whether board, which has a single solution, is
+// minimal.
+func isMinimalBoard(board Values) bool {
+	minimal, _, err := IsMinimal(board)
+	return err == nil && minimal
 }
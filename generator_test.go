@@ -1,9 +1,12 @@
 package sudoku
 
 import (
+	"errors"
 	"math/rand"
 	"testing"
 	"time"
+
+	"golang.org/x/exp/slices"
 )
 
 func TestGenerate(t *testing.T) {
@@ -38,3 +41,225 @@ func TestGenerateSymmetrical(t *testing.T) {
 		}
 	}
 }
+
+func TestGenerateWithSymmetry(t *testing.T) {
+	for sym := SymmetryNone; sym <= SymmetryDihedral; sym++ {
+		board := GenerateWithSymmetry(30, sym)
+		vs := SolveAll(board, 2)
+		if len(vs) != 1 {
+			t.Errorf("got %v solutions, want 1", len(vs))
+		}
+		if !HasSymmetry(board, sym) {
+			t.Errorf("got board without %v symmetry:\n%v", sym, Display(board))
+		}
+	}
+}
+
+func TestGenerateWithDifficulty(t *testing.T) {
+	var tests = []struct {
+		min, max float64
+		opts     GenerateOptions
+	}{
+		{2.0, 2.5, GenerateOptions{}},
+		{3.0, 3.5, GenerateOptions{Symmetry: SymmetryRotational180}},
+		{2.0, 3.5, GenerateOptions{Symmetry: SymmetryDiagonal}},
+		{1.0, 5.0, GenerateOptions{MinHints: 40}},
+		{0, 1.5, GenerateOptions{}},
+		{1.0, 5.0, GenerateOptions{MaxHints: 30}},
+	}
+
+	for _, tt := range tests {
+		res, err := GenerateWithDifficulty(tt.min, tt.max, tt.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Difficulty < tt.min || res.Difficulty > tt.max {
+			t.Errorf("got difficulty %v, want [%v, %v]", res.Difficulty, tt.min, tt.max)
+		}
+		if res.Tried < 1 {
+			t.Errorf("got Tried=%v", res.Tried)
+		}
+		if n := len(SolveAll(res.Board, 2)); n != 1 {
+			t.Errorf("got %v solutions, want 1", n)
+		}
+		hints := CountHints(res.Board)
+		if hints < tt.opts.MinHints {
+			t.Errorf("got %v hints, want at least %v", hints, tt.opts.MinHints)
+		}
+		if hints == 81 || (tt.opts.MaxHints > 0 && hints > tt.opts.MaxHints) {
+			t.Errorf("got %v hints, want fewer than 81 and at most %v", hints, tt.opts.MaxHints)
+		}
+		if !HasSymmetry(res.Board, tt.opts.Symmetry) {
+			t.Errorf("got board without %v symmetry:\n%v", tt.opts.Symmetry, Display(res.Board))
+		}
+	}
+}
+
+func TestGenerateWithDifficultySeeded(t *testing.T) {
+	generate := func() GenerateResult {
+		res, err := GenerateWithDifficulty(3.0, 3.5, GenerateOptions{
+			Difficulty: DifficultyOptions{Mode: SearchSeeded, Seed: 1},
+			Rand:       rand.New(rand.NewSource(42)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	res1 := generate()
+	res2 := generate()
+	if !slices.Equal(res1.Board, res2.Board) || res1.Tried != res2.Tried {
+		t.Errorf("got different boards with the same seed:\n%v\n%v", DisplayAsInput(res1.Board), DisplayAsInput(res2.Board))
+	}
+}
+
+func TestGenerateWithDifficultyErrors(t *testing.T) {
+	if _, err := GenerateWithDifficulty(3.0, 2.0, GenerateOptions{}); err == nil {
+		t.Errorf("got no error for invalid range")
+	}
+
+	// No board has this difficulty with so many hints.
+	res, err := GenerateWithDifficulty(4.5, 5.0, GenerateOptions{MinHints: 50, Timeout: 50 * time.Millisecond})
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("got err %v, want ErrTimeout", err)
+	}
+	if res.Tried < 1 {
+		t.Errorf("got Tried=%v", res.Tried)
+	}
+
+	res, err = GenerateWithDifficulty(4.5, 5.0, GenerateOptions{MinHints: 50, MaxTries: 20})
+	if !errors.Is(err, ErrTimeout) || res.Tried != 20 {
+		t.Errorf("got err %v after %v tries, want ErrTimeout after 20", err, res.Tried)
+	}
+}
+
+func TestGenerateWithTechniques(t *testing.T) {
+	var tests = []TechniqueRequirement{
+		{MaxRating: 3.0},
+		{Required: []Technique{NakedPair}},
+		{Required: []Technique{LockedCandidates, HiddenPair}, MaxRating: 4.0},
+		{Required: []Technique{XWing}},
+	}
+	if testing.Short() {
+		tests = tests[:3]
+	}
+
+	for _, req := range tests {
+		res, err := GenerateWithTechniques(req, GenerateOptions{Rand: rand.New(rand.NewSource(1))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := len(SolveAll(res.Board, 2)); n != 1 {
+			t.Errorf("got %v solutions, want 1", n)
+		}
+		if CountHints(res.Board) == 81 {
+			t.Errorf("got full board for %+v", req)
+		}
+
+		// Check the trace against an independent rating of the board.
+		r, err := EvaluateTechniqueDifficulty(res.Board)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !r.Solved || r.Rating != res.Difficulty || len(r.Steps) != len(res.Techniques.Steps) {
+			t.Errorf("got rating %v with %v steps, want %v with %v steps", res.Difficulty, len(res.Techniques.Steps), r.Rating, len(r.Steps))
+		}
+		for _, tech := range req.Required {
+			if r.Histogram[tech] == 0 {
+				t.Errorf("%v not used in %v", tech, r.Histogram)
+			}
+			if r.Rating > tech.Rating() && req.MaxRating == 0 {
+				t.Errorf("got hardest technique %v, harder than %v", r.Hardest, tech)
+			}
+		}
+		if req.MaxRating != 0 && r.Rating > req.MaxRating {
+			t.Errorf("got rating %v, want at most %v", r.Rating, req.MaxRating)
+		}
+	}
+}
+
+func TestGenerateWithTechniquesErrors(t *testing.T) {
+	for _, req := range []TechniqueRequirement{
+		{Required: []Technique{Swordfish}, MaxRating: 3.0},
+		{Required: []Technique{Technique(1000)}},
+	} {
+		if _, err := GenerateWithTechniques(req, GenerateOptions{}); err == nil {
+			t.Errorf("got no error for %+v", req)
+		}
+	}
+}
+
+func TestIsMinimal(t *testing.T) {
+	// A minimal board stays minimal, and each hint added to it is redundant.
+	board := Generate(0)
+	minimal, redundant, err := IsMinimal(board)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !minimal || len(redundant) > 0 {
+		t.Errorf("got minimal=%v, redundant=%v for Generate(0)", minimal, redundant)
+	}
+
+	solution := SolveAll(board, 1)[0]
+	var added []Index
+	for sq := 0; sq < 81 && len(added) < 3; sq++ {
+		if board[sq].Size() != 1 {
+			board[sq] = solution[sq]
+			added = append(added, sq)
+		}
+	}
+	minimal, redundant, err = IsMinimal(board)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if minimal {
+		t.Errorf("got minimal board with redundant hints")
+	}
+	for _, sq := range added {
+		if !slices.Contains(redundant, sq) {
+			t.Errorf("added hint %v not reported as redundant in %v", sq, redundant)
+		}
+	}
+
+	// The filled board has only redundant hints.
+	v, err := ParseBoard(filled, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, redundant, _ := IsMinimal(v); len(redundant) != 81 {
+		t.Errorf("got %v redundant hints in filled board, want 81", len(redundant))
+	}
+
+	if _, _, err := IsMinimal(EmptyBoard()); !errors.Is(err, ErrMultipleSolutions) {
+		t.Errorf("got err %v, want ErrMultipleSolutions", err)
+	}
+
+	// A board with the same digit twice in a row has no solution.
+	unsolvable := EmptyBoard()
+	unsolvable[0], unsolvable[1] = SingleDigitSet(1), SingleDigitSet(1)
+	if _, _, err := IsMinimal(unsolvable); !errors.Is(err, ErrNoSolution) {
+		t.Errorf("got err %v, want ErrNoSolution", err)
+	}
+}
+
+func TestGenerateMinimal(t *testing.T) {
+	res, err := GenerateWithDifficulty(2.5, 5.0, GenerateOptions{Minimal: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if minimal, redundant, err := IsMinimal(res.Board); err != nil || !minimal {
+		t.Errorf("got non-minimal board, redundant hints %v, err %v", redundant, err)
+	}
+
+	res, err = GenerateWithTechniques(TechniqueRequirement{Required: []Technique{LockedCandidates}}, GenerateOptions{Minimal: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if minimal, _, _ := IsMinimal(res.Board); !minimal {
+		t.Errorf("got non-minimal board")
+	}
+	if res.Techniques.Histogram[LockedCandidates] == 0 {
+		t.Errorf("got board without locked candidates")
+	}
+}
@@ -0,0 +1,175 @@
+package sudoku
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/exp/slices"
+)
+
+// HintKind describes what a Hint is about.
+type HintKind int
+
+const (
+	// HintStep is a hint about the next logical step.
+	HintStep HintKind = iota
+
+	// HintWrongEntries means that some of the player's entries contradict the
+	// solution of the puzzle; Hint.Wrong lists them.
+	HintWrongEntries
+
+	// HintSolved means that the board is already solved.
+	HintSolved
+)
+
+// Hint is the result of NextHint.
+type Hint struct {
+	Kind HintKind
+
+	// Step is the logical step the hint is about, for HintStep hints.
+	Step Step
+
+	// Focus lists the squares the player should look at.
+	Focus []Index
+
+	// Levels is an escalating sequence of hint texts: a nudge that points at
+	// the relevant area of the board, the name of the technique to use, and
+	// finally the exact deduction.
+	Levels []string
+
+	// Wrong lists squares filled in by the player with digits that don't
+	// match the solution of the puzzle.
+	Wrong []Index
+
+	// WrongCandidates lists squares where the player eliminated the digit of
+	// the solution from the candidates. The hint is computed as if these
+	// squares had all their candidates.
+	WrongCandidates []Index
+}
+
+// ErrMultipleSolutions is returned when a puzzle that has to have a unique
+// solution has more than one.
+var ErrMultipleSolutions = errors.New("puzzle has more than one solution")
+
+// ErrNoSolution is returned when a puzzle that has to have a unique solution
+// has none.
+var ErrNoSolution = errors.New("puzzle has no solution")
+
+// NextHint finds a hint for the player solving puzzle, who reached the board
+// in current. puzzle holds the givens (as returned by ParseBoard without
+// elimination); current holds the givens, the digits filled in by the player
+// as squares with a single candidate, and optionally the player's pencil marks
+// as the candidates of the other squares (squares with no candidates are
+// treated as having all of them).
+//
+// The hint is about the easiest logical step that applies on the board; the
+// puzzle must have a unique solution. If some of the player's entries are
+// wrong, the hint reports them instead. NextHint returns an error if the
+// puzzle has no solution or more than one, or if current doesn't match the
+// givens of puzzle.
+func NextHint(puzzle Values, current Values) (Hint, error) {
+	if len(puzzle) != 81 || len(current) != 81 {
+		return Hint{}, fmt.Errorf("got boards with %v and %v squares, want 81", len(puzzle), len(current))
+	}
+
+	vcopy := slices.Clone(puzzle)
+	if !EliminateAll(vcopy) {
+		return Hint{}, fmt.Errorf("contradiction in puzzle")
+	}
+	solutions := SolveAll(vcopy, 2)
+	switch len(solutions) {
+	case 0:
+		return Hint{}, ErrNoSolution
+	case 1:
+	default:
+		return Hint{}, ErrMultipleSolutions
+	}
+	solution := solutions[0]
+
+	var hint Hint
+	values := slices.Clone(current)
+	var placed [81]bool
+	for sq, d := range values {
+		if puzzle[sq].Size() == 1 && d != puzzle[sq] {
+			return Hint{}, fmt.Errorf("square %s doesn't match the given %s", squareName(sq), puzzle[sq])
+		}
+		switch {
+		case d.Size() == 1:
+			if d != solution[sq] {
+				hint.Wrong = append(hint.Wrong, sq)
+			}
+			placed[sq] = true
+		case d == 0:
+			values[sq] = FullDigitsSet()
+		case !d.IsMember(solution[sq].SingleMemberDigit()):
+			hint.WrongCandidates = append(hint.WrongCandidates, sq)
+			values[sq] = FullDigitsSet()
+		}
+	}
+
+	if len(hint.Wrong) > 0 {
+		hint.Kind = HintWrongEntries
+		hint.Focus = hint.Wrong
+		hint.Levels = []string{
+			"Some of your entries are wrong",
+			fmt.Sprintf("Check your entries in %s", squareNames(hint.Wrong)),
+		}
+		return hint, nil
+	}
+	if !slices.Contains(placed[:], false) {
+		hint.Kind = HintSolved
+		hint.Levels = []string{"The puzzle is solved"}
+		return hint, nil
+	}
+
+	// Pencil marks may be missing eliminations implied by the entries; remove
+	// placed digits from their peers before looking for a step.
+	for sq := range values {
+		if placed[sq] {
+			for _, peer := range peers[sq] {
+				if !placed[peer] {
+					values[peer] = values[peer].RemoveAll(values[sq])
+				}
+			}
+		}
+	}
+
+	step, ok := nextStep(values, &placed, LogicOptions{AssumeUnique: true})
+	if !ok {
+		// This shouldn't happen with forcing nets, but fall back to revealing a
+		// square with the fewest candidates.
+		sq := findSquareWithFewestCandidates(values)
+		c := Candidate{sq, solution[sq].SingleMemberDigit()}
+		step = Step{
+			Technique:  NakedSingle,
+			Placements: []Candidate{c},
+			Cells:      []Index{sq},
+			Digits:     solution[sq],
+		}
+		describe(&step, "no logical step found; the solution has %s", c)
+	}
+
+	hint.Kind = HintStep
+	hint.Step = step
+	hint.Focus = step.Cells
+	hint.Levels = []string{nudge(step), fmt.Sprintf("Try using a %s", step.Technique), step.Description}
+	return hint, nil
+}
+
+// nudge returns a vague hint for step that points at the relevant area of the
+// board without giving the deduction away.
+func nudge(step Step) string {
+	// Find a unit that contains all the squares of the step's pattern; prefer
+	// boxes, then rows and columns.
+	for _, u := range []int{18, 0, 9} {
+		for offset := 0; offset < 9; offset++ {
+			if len(step.Cells) > 0 && containsAll(unitlist[u+offset], step.Cells) {
+				return fmt.Sprintf("Look closely at %s", unitName(u+offset))
+			}
+		}
+	}
+	if step.Digits.Size() == 1 {
+		return fmt.Sprintf("Think about where %s can go", step.Digits)
+	}
+	return fmt.Sprintf("Look closely at %s", squareNames(step.Cells))
+}
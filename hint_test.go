@@ -0,0 +1,118 @@
+package sudoku
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestNextHint(t *testing.T) {
+	puzzle, err := ParseBoard(hardboard2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	solution := SolveAll(puzzle, 2)[0]
+
+	// Follow hints until the puzzle is solved, filling in placements and
+	// keeping the candidates of unsolved squares as pencil marks.
+	current := slices.Clone(puzzle)
+	for i := 0; ; i++ {
+		if i > 500 {
+			t.Fatalf("too many hints")
+		}
+		hint, err := NextHint(puzzle, current)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hint.Kind == HintSolved {
+			break
+		}
+		if hint.Kind != HintStep {
+			t.Fatalf("got hint kind %v, want HintStep", hint.Kind)
+		}
+		if len(hint.Levels) != 3 || !strings.Contains(hint.Levels[1], hint.Step.Technique.String()) {
+			t.Errorf("got levels %q", hint.Levels)
+		}
+		if !slices.Equal(hint.Focus, hint.Step.Cells) {
+			t.Errorf("got focus %v, want %v", hint.Focus, hint.Step.Cells)
+		}
+		checkStepsAgainstSolution(t, []Step{hint.Step}, solution)
+
+		// Apply the step to a copy with full candidates for unsolved squares, to
+		// also exercise the pencil mark handling.
+		for _, p := range hint.Step.Placements {
+			current[p.Square] = SingleDigitSet(p.Digit)
+		}
+		for _, e := range hint.Step.Eliminations {
+			if current[e.Square].Size() > 1 {
+				current[e.Square] = current[e.Square].Remove(e.Digit)
+			}
+		}
+	}
+
+	if !slices.Equal(current, solution) {
+		t.Errorf("following hints didn't reach the solution")
+	}
+}
+
+func TestNextHintWrongEntries(t *testing.T) {
+	puzzle, err := ParseBoard(easyboard1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	solution := SolveAll(puzzle, 2)[0]
+
+	current := slices.Clone(puzzle)
+	current[0] = SingleDigitSet(solution[0].SingleMemberDigit()%9 + 1)
+	current[1] = solution[1]
+
+	hint, err := NextHint(puzzle, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hint.Kind != HintWrongEntries || !slices.Equal(hint.Wrong, []Index{0}) {
+		t.Errorf("got hint %v, want wrong entry in square 0", hint)
+	}
+
+	// Pencil marks without the solution digit are reported, but don't stop the
+	// hint.
+	current = slices.Clone(puzzle)
+	current[0] = FullDigitsSet().RemoveAll(solution[0])
+	hint, err = NextHint(puzzle, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hint.Kind != HintStep || !slices.Equal(hint.WrongCandidates, []Index{0}) {
+		t.Errorf("got hint %v, want wrong candidates in square 0", hint)
+	}
+}
+
+func TestNextHintErrors(t *testing.T) {
+	puzzle, err := ParseBoard(easyboard1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	solution := SolveAll(puzzle, 2)[0]
+
+	hint, err := NextHint(puzzle, solution)
+	if err != nil || hint.Kind != HintSolved {
+		t.Errorf("got hint %v, err %v; want HintSolved", hint, err)
+	}
+
+	// Changing a given is an error.
+	current := slices.Clone(puzzle)
+	current[2] = FullDigitsSet()
+	if _, err := NextHint(puzzle, current); err == nil {
+		t.Errorf("expect error for changed given")
+	}
+
+	multi, err := ParseBoard("123456789"+strings.Repeat(".", 72), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NextHint(multi, multi); !errors.Is(err, ErrMultipleSolutions) {
+		t.Errorf("got err %v, want ErrMultipleSolutions", err)
+	}
+}
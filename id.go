@@ -0,0 +1,182 @@
+package sudoku
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"math/big"
+	"math/rand"
+
+	"golang.org/x/exp/slices"
+)
+
+// Puzzle IDs are short URL-safe strings for sharing puzzles, e.g. in links.
+// An ID is the unpadded URL-safe base64 encoding of a version byte, a
+// payload that depends on the version, and a checksum byte (the low byte of
+// the CRC-32 of the version and payload).
+const (
+	// idVersionClues IDs encode the clues of a puzzle: the payload is a big
+	// integer with an 81-bit mask of the clue squares in its low bits, and
+	// the clue digits above them in base 9, the first clue most significant.
+	idVersionClues byte = 1
+
+	// idVersionSeed IDs encode a PuzzleSeed: the payload is the seed as a
+	// varint, the symmetry, and the minimal and maximal difficulty in tenths.
+	idVersionSeed byte = 2
+)
+
+// Encode returns the ID of the puzzle: a short URL-safe string that Decode
+// turns back into the puzzle. Only squares with a single digit count as clues.
+func Encode(values Values) string {
+	var n, mask big.Int
+	for sq, d := range values {
+		if d.Size() == 1 {
+			mask.SetBit(&mask, sq, 1)
+			n.Mul(&n, big.NewInt(9))
+			n.Add(&n, big.NewInt(int64(d.SingleMemberDigit()-1)))
+		}
+	}
+	n.Lsh(&n, 81)
+	n.Or(&n, &mask)
+	return encodeID(idVersionClues, n.Bytes())
+}
+
+// PuzzleSeed describes a puzzle generated deterministically from a seed, with
+// GenerateWithDifficulty. Its ID, from EncodeSeed, is shorter than the ID of
+// the puzzle itself, but decoding it takes as long as generating the puzzle,
+// and the puzzle it describes may change when the generator does.
+type PuzzleSeed struct {
+	Seed int64
+
+	// Symmetry is the symmetry of the hints of the puzzle.
+	Symmetry Symmetry
+
+	// MinDifficulty and MaxDifficulty are the range of difficulty of the
+	// puzzle, with 0 < MinDifficulty <= MaxDifficulty <= 5; IDs store them
+	// rounded to tenths.
+	MinDifficulty, MaxDifficulty float64
+}
+
+// seedMaxTries limits the work PuzzleSeed.Generate does, since seeds may come
+// from untrusted IDs. It's a number of tries rather than a time, so that a
+// seed generates the same puzzle, or fails, on every machine.
+const seedMaxTries = 25000
+
+// Generate generates the puzzle described by p. It always generates the same
+// puzzle for the same p, or always returns ErrTimeout if that takes more than
+// seedMaxTries candidate boards (about 10 seconds on a fast machine). It
+// returns an error if p is invalid.
+func (p PuzzleSeed) Generate() (Values, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	res, err := GenerateWithDifficulty(p.MinDifficulty, p.MaxDifficulty, GenerateOptions{
+		Symmetry:   p.Symmetry,
+		MaxTries:   seedMaxTries,
+		Difficulty: DifficultyOptions{Mode: SearchSeeded, Seed: p.Seed},
+		Rand:       rand.New(rand.NewSource(p.Seed)),
+	})
+	return res.Board, err
+}
+
+// validate returns an error if p doesn't describe a puzzle.
+func (p PuzzleSeed) validate() error {
+	if p.Symmetry < SymmetryNone || p.Symmetry > SymmetryDihedral {
+		return fmt.Errorf("unknown symmetry %d", int(p.Symmetry))
+	}
+	if !(p.MinDifficulty > 0 && p.MinDifficulty <= p.MaxDifficulty && p.MaxDifficulty <= 5) {
+		return fmt.Errorf("invalid difficulty range [%v, %v], want 0 < min <= max <= 5", p.MinDifficulty, p.MaxDifficulty)
+	}
+	return nil
+}
+
+// EncodeSeed returns the ID of the puzzle described by p; Decode generates
+// the puzzle from it.
+func EncodeSeed(p PuzzleSeed) string {
+	tenths := func(f float64) byte {
+		return byte(math.Round(math.Max(0, math.Min(f*10, math.MaxUint8))))
+	}
+	payload := binary.AppendVarint(nil, p.Seed)
+	payload = append(payload, byte(p.Symmetry), tenths(p.MinDifficulty), tenths(p.MaxDifficulty))
+	return encodeID(idVersionSeed, payload)
+}
+
+// encodeID returns the ID with the given version and payload.
+func encodeID(version byte, payload []byte) string {
+	data := append([]byte{version}, payload...)
+	data = append(data, byte(crc32.ChecksumIEEE(data)))
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode returns the puzzle with the given ID, as returned by Encode or
+// EncodeSeed. For IDs from EncodeSeed, the puzzle is generated.
+func Decode(id string) (Values, error) {
+	data, err := base64.RawURLEncoding.DecodeString(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid puzzle ID: %w", err)
+	}
+	if len(data) < 2 {
+		return nil, errors.New("invalid puzzle ID: too short")
+	}
+	body, checksum := data[:len(data)-1], data[len(data)-1]
+	if byte(crc32.ChecksumIEEE(body)) != checksum {
+		return nil, errors.New("invalid puzzle ID: checksum mismatch")
+	}
+
+	version, payload := body[0], body[1:]
+	switch version {
+	case idVersionClues:
+		return decodeClues(payload)
+	case idVersionSeed:
+		seed, n := binary.Varint(payload)
+		if n <= 0 || len(payload) != n+3 {
+			return nil, errors.New("invalid puzzle ID: bad seed")
+		}
+		p := PuzzleSeed{
+			Seed:          seed,
+			Symmetry:      Symmetry(payload[n]),
+			MinDifficulty: float64(payload[n+1]) / 10,
+			MaxDifficulty: float64(payload[n+2]) / 10,
+		}
+		if err := p.validate(); err != nil {
+			return nil, fmt.Errorf("invalid puzzle ID: %w", err)
+		}
+		return p.Generate()
+	}
+	return nil, fmt.Errorf("invalid puzzle ID: unknown version %d", version)
+}
+
+// decodeClues decodes the payload of an idVersionClues ID.
+func decodeClues(payload []byte) (Values, error) {
+	var n big.Int
+	n.SetBytes(payload)
+
+	var clueSquares []Index
+	for sq := 0; sq < 81; sq++ {
+		if n.Bit(sq) == 1 {
+			clueSquares = append(clueSquares, sq)
+		}
+	}
+	n.Rsh(&n, 81)
+
+	values := EmptyBoard()
+	var digit big.Int
+	nine := big.NewInt(9)
+	for i := len(clueSquares) - 1; i >= 0; i-- {
+		n.DivMod(&n, nine, &digit)
+		values[clueSquares[i]] = SingleDigitSet(uint16(digit.Int64() + 1))
+	}
+	if n.Sign() != 0 {
+		return nil, errors.New("invalid puzzle ID: too many digits")
+	}
+
+	// The checksum only catches most corruptions; one that's missed is likely
+	// to put the same digit twice in a unit.
+	if !EliminateAll(slices.Clone(values)) {
+		return nil, errors.New("invalid puzzle ID: inconsistent clues")
+	}
+	return values, nil
+}
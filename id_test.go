@@ -0,0 +1,86 @@
+package sudoku
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	for _, board := range []string{easyboard1, hardboard1, hardboard2, filled, strings.Repeat(".", 81)} {
+		v, err := ParseBoard(board, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := Encode(v)
+		if strings.ContainsAny(id, "+/=") {
+			t.Errorf("got ID %q that isn't URL-safe", id)
+		}
+		got, err := Decode(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, v) {
+			t.Errorf("got different board after round trip:\n%v", DisplayAsInput(got))
+		}
+	}
+
+	// A 17-clue puzzle has a short ID.
+	v, err := ParseBoard(hardboard1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := Encode(v); len(id) > 26 {
+		t.Errorf("got ID %q of length %v for 17 clues", id, len(id))
+	}
+}
+
+func TestEncodeSeed(t *testing.T) {
+	p := PuzzleSeed{Seed: 12345, Symmetry: SymmetryDiagonal, MinDifficulty: 2.0, MaxDifficulty: 3.0}
+	want, err := p.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := EncodeSeed(p)
+	got, err := Decode(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got different board from seed ID %q:\n%v\n%v", id, DisplayAsInput(got), DisplayAsInput(want))
+	}
+	if !HasSymmetry(got, SymmetryDiagonal) {
+		t.Errorf("got board without symmetry from seed ID")
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	v, err := ParseBoard(hardboard1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := Encode(v)
+
+	// Changing a character breaks the checksum.
+	corrupted := []byte(id)
+	if corrupted[5] == 'A' {
+		corrupted[5] = 'B'
+	} else {
+		corrupted[5] = 'A'
+	}
+
+	// An ID with a valid checksum, but the same digit twice in a row.
+	inconsistent := EmptyBoard()
+	inconsistent[0], inconsistent[5] = SingleDigitSet(4), SingleDigitSet(4)
+
+	for _, bad := range []string{"", "A", "!!!", string(corrupted), encodeID(9, nil), Encode(inconsistent),
+		encodeID(idVersionSeed, []byte{1}), encodeID(idVersionSeed, []byte{2, 100, 10, 20}),
+		EncodeSeed(PuzzleSeed{Seed: 1, MinDifficulty: 6, MaxDifficulty: 7}),
+		EncodeSeed(PuzzleSeed{Seed: 1, MinDifficulty: 0, MaxDifficulty: 1.5}),
+		EncodeSeed(PuzzleSeed{Seed: 1, MinDifficulty: 3, MaxDifficulty: 2})} {
+		if _, err := Decode(bad); err == nil {
+			t.Errorf("got no error for ID %q", bad)
+		}
+	}
+}
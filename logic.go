@@ -0,0 +1,813 @@
+package sudoku
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// This file contains a logical (human-style) solver. Unlike Solve, which uses
+// constraint propagation and backtracking search, the logical solver applies
+// one named technique at a time and records each deduction as a Step, so the
+// solution path can be explained, rated and replayed.
+//
+// The logical solver operates on a board of candidates (Values) and keeps track
+// of which squares were placed (either given or placed by a step). Squares with
+// a single candidate that weren't placed yet are "naked singles".
+
+// Technique identifies a solving technique used by the logical solver.
+type Technique int
+
+const (
+	NakedSingle Technique = iota
+	HiddenSingle
+	LockedCandidates
+	NakedPair
+	HiddenPair
+	NakedTriple
+	HiddenTriple
+	NakedQuad
+	HiddenQuad
+	XWing
+	Swordfish
+	Jellyfish
+	UniqueRectangle1
+	UniqueRectangle2
+	UniqueRectangle3
+	UniqueRectangle4
+	UniqueRectangle5
+	UniqueRectangle6
+	BUGPlusOne
+	ALSXZ
+	ALSXYWing
+	DeathBlossom
+	DigitForcingChain
+	CellForcingChain
+	UnitForcingChain
+	DigitForcingNet
+	CellForcingNet
+	UnitForcingNet
+)
+
+var techniqueNames = map[Technique]string{
+	NakedSingle:       "Naked Single",
+	HiddenSingle:      "Hidden Single",
+	LockedCandidates:  "Locked Candidates",
+	NakedPair:         "Naked Pair",
+	HiddenPair:        "Hidden Pair",
+	NakedTriple:       "Naked Triple",
+	HiddenTriple:      "Hidden Triple",
+	NakedQuad:         "Naked Quad",
+	HiddenQuad:        "Hidden Quad",
+	XWing:             "X-Wing",
+	Swordfish:         "Swordfish",
+	Jellyfish:         "Jellyfish",
+	UniqueRectangle1:  "Unique Rectangle Type 1",
+	UniqueRectangle2:  "Unique Rectangle Type 2",
+	UniqueRectangle3:  "Unique Rectangle Type 3",
+	UniqueRectangle4:  "Unique Rectangle Type 4",
+	UniqueRectangle5:  "Unique Rectangle Type 5",
+	UniqueRectangle6:  "Unique Rectangle Type 6",
+	BUGPlusOne:        "BUG+1",
+	ALSXZ:             "ALS-XZ",
+	ALSXYWing:         "ALS-XY-Wing",
+	DeathBlossom:      "Death Blossom",
+	DigitForcingChain: "Digit Forcing Chain",
+	CellForcingChain:  "Cell Forcing Chain",
+	UnitForcingChain:  "Unit Forcing Chain",
+	DigitForcingNet:   "Digit Forcing Net",
+	CellForcingNet:    "Cell Forcing Net",
+	UnitForcingNet:    "Unit Forcing Net",
+}
+
+// String implements the fmt.Stringer interface for Technique.
+func (t Technique) String() string {
+	if name, ok := techniqueNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("Technique(%d)", int(t))
+}
+
+// ParseTechnique returns the technique with the given name, as returned by
+// Technique.String. The comparison ignores case, spaces and hyphens, so
+// "xwing" and "naked-pair" are accepted.
+func ParseTechnique(name string) (Technique, error) {
+	normalize := func(s string) string {
+		s = strings.ToLower(s)
+		return strings.NewReplacer(" ", "", "-", "").Replace(s)
+	}
+	for t, tname := range techniqueNames {
+		if normalize(tname) == normalize(name) {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown technique %q", name)
+}
+
+// RequiresUniqueness reports whether t is only valid for puzzles that are
+// known to have a single solution.
+func (t Technique) RequiresUniqueness() bool {
+	return t >= UniqueRectangle1 && t <= BUGPlusOne
+}
+
+// Candidate is a single candidate digit in a single square.
+type Candidate struct {
+	Square Index
+	Digit  uint16
+}
+
+// String implements the fmt.Stringer interface for Candidate.
+func (c Candidate) String() string {
+	return fmt.Sprintf("%s=%d", squareName(c.Square), c.Digit)
+}
+
+// Step is a single deduction made by the logical solver.
+type Step struct {
+	Technique Technique
+
+	// Placements lists candidates that this step proves to be the solution of
+	// their squares.
+	Placements []Candidate
+
+	// Eliminations lists candidates that this step proves to be impossible.
+	Eliminations []Candidate
+
+	// Cells lists the squares of the pattern that justifies this step (e.g. the
+	// four corners of a unique rectangle), for highlighting.
+	Cells []Index
+
+	// Digits is the set of digits the pattern is built on.
+	Digits Digits
+
+	// ALS lists the Almost Locked Sets used by ALS-based techniques, so that
+	// they can be highlighted separately.
+	ALS []ALS
+
+	// Implications holds, for forcing chains and nets, the final implication
+	// of each branch of the assumptions made (a contradiction or the
+	// conclusion); following the Parent links leads back to the assumption.
+	Implications []*Implication
+
+	// Description is a short human-readable explanation of the step.
+	Description string
+}
+
+// LogicOptions is a container of options for the logical solver.
+type LogicOptions struct {
+	// AssumeUnique enables techniques that are only valid when the puzzle is
+	// known to have a single solution, such as Unique Rectangles and BUG+1.
+	// It's off by default.
+	AssumeUnique bool
+
+	// ForcingNetDepth limits how many implications forcing nets follow from
+	// their assumption; zero means no limit. Forcing chains are always limited
+	// to a small depth.
+	ForcingNetDepth int
+
+	// MaxRating, if not zero, restricts the solver to techniques with a rating
+	// (see Technique.Rating) of at most MaxRating.
+	MaxRating float64
+}
+
+// strategy looks for a single application of a technique on values, and
+// returns nil if the technique doesn't apply. It must not modify values.
+type strategy func(values Values) *Step
+
+// logicStrategy pairs a strategy with the conditions under which it may run.
+// Strategies that depend on the solver's options use findWithOptions instead
+// of find. technique is the easiest technique the strategy finds.
+type logicStrategy struct {
+	find            strategy
+	findWithOptions func(values Values, opts LogicOptions) *Step
+	technique       Technique
+	assumeUnique    bool
+}
+
+// logicStrategies lists all the strategies of the logical solver, from the
+// easiest to the hardest (in the order of their ratings; see Technique.Rating).
+// NextStep returns the first one that applies. Naked singles are handled
+// separately by nextStep since they depend on which squares were already
+// placed.
+var logicStrategies = []logicStrategy{
+	{find: findLockedCandidates, technique: LockedCandidates},
+	{find: findNakedSubset(2), technique: NakedPair},
+	{find: findFish(2), technique: XWing},
+	{find: findHiddenSubset(2), technique: HiddenPair},
+	{find: findNakedSubset(3), technique: NakedTriple},
+	{find: findFish(3), technique: Swordfish},
+	{find: findHiddenSubset(3), technique: HiddenTriple},
+	{find: findUniqueRectangle1, technique: UniqueRectangle1, assumeUnique: true},
+	{find: findUniqueRectangle2, technique: UniqueRectangle2, assumeUnique: true},
+	{find: findUniqueRectangle4, technique: UniqueRectangle4, assumeUnique: true},
+	{find: findUniqueRectangle5, technique: UniqueRectangle5, assumeUnique: true},
+	{find: findUniqueRectangle6, technique: UniqueRectangle6, assumeUnique: true},
+	{find: findUniqueRectangle3, technique: UniqueRectangle3, assumeUnique: true},
+	{find: findNakedSubset(4), technique: NakedQuad},
+	{find: findFish(4), technique: Jellyfish},
+	{find: findHiddenSubset(4), technique: HiddenQuad},
+	{find: findBUGPlusOne, technique: BUGPlusOne, assumeUnique: true},
+	{find: findALSXZ, technique: ALSXZ},
+	{find: findALSXYWing, technique: ALSXYWing},
+	{find: findDeathBlossom, technique: DeathBlossom},
+	{findWithOptions: findForcingChains, technique: DigitForcingChain},
+	{findWithOptions: findForcingNets, technique: DigitForcingNet},
+}
+
+// NextStep finds the easiest logical step that can be applied to values, and
+// returns it with true; it returns false if no known technique applies. values
+// is not modified.
+// values should be a board of candidates, where placed digits have already
+// been removed from their peers (as done by SolveLogically). Since values
+// doesn't record which squares were placed, squares with a single candidate
+// that still appears in a peer are reported as naked singles.
+func NextStep(values Values, options ...LogicOptions) (Step, bool) {
+	if len(options) > 1 {
+		panic("NextStep cannot accept more than a single LogicOptions")
+	}
+	var opts LogicOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	return nextStep(values, nil, opts)
+}
+
+// nextStep implements NextStep. placed marks the squares that were already
+// placed; if it's nil, it's inferred from values.
+func nextStep(values Values, placed *[81]bool, opts LogicOptions) (Step, bool) {
+	allowed := func(step *Step) bool {
+		return step != nil && (opts.MaxRating == 0 || step.Technique.Rating() <= opts.MaxRating)
+	}
+
+	// Hidden singles are considered easier than naked singles.
+	if step := findHiddenSingle(values); allowed(step) {
+		return *step, true
+	}
+	if step := findNakedSingle(values, placed); allowed(step) {
+		return *step, true
+	}
+	for _, s := range logicStrategies {
+		if opts.MaxRating != 0 && s.technique.Rating() > opts.MaxRating {
+			// The strategies are ordered by rating.
+			break
+		}
+		if s.assumeUnique && !opts.AssumeUnique {
+			continue
+		}
+		var step *Step
+		if s.find != nil {
+			step = s.find(values)
+		} else {
+			step = s.findWithOptions(values, opts)
+		}
+		if allowed(step) {
+			return *step, true
+		}
+	}
+	return Step{}, false
+}
+
+// ApplyStep applies step to values, which is modified. Placed digits are
+// removed from the peers of their squares. It returns false if applying the
+// step results in a contradiction.
+func ApplyStep(values Values, step Step) bool {
+	for _, p := range step.Placements {
+		if !values[p.Square].IsMember(p.Digit) {
+			return false
+		}
+		values[p.Square] = SingleDigitSet(p.Digit)
+		for _, peer := range peers[p.Square] {
+			values[peer] = values[peer].Remove(p.Digit)
+			if values[peer] == 0 {
+				return false
+			}
+		}
+	}
+	for _, e := range step.Eliminations {
+		values[e.Square] = values[e.Square].Remove(e.Digit)
+		if values[e.Square] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// SolveLogically solves the board given in values using only the techniques
+// of the logical solver, without guessing. It returns the resulting board,
+// the steps taken and true if the board was solved; if the solver got stuck
+// (or found a contradiction) it returns the partially solved board, the steps
+// taken so far and false. The input values is not modified.
+// values may or may not have had elimination applied to it.
+func SolveLogically(values Values, options ...LogicOptions) (Values, []Step, bool) {
+	if len(options) > 1 {
+		panic("SolveLogically cannot accept more than a single LogicOptions")
+	}
+
+	var opts LogicOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+
+	vcopy := slices.Clone(values)
+	var placed [81]bool
+	for sq, d := range vcopy {
+		placed[sq] = d.Size() == 1
+	}
+	if !removeSolvedFromPeers(vcopy) {
+		return vcopy, nil, false
+	}
+
+	var steps []Step
+	for slices.Contains(placed[:], false) {
+		step, ok := nextStep(vcopy, &placed, opts)
+		if !ok || !ApplyStep(vcopy, step) {
+			return vcopy, steps, false
+		}
+		for _, p := range step.Placements {
+			placed[p.Square] = true
+		}
+		steps = append(steps, step)
+	}
+	return vcopy, steps, IsSolved(vcopy)
+}
+
+// removeSolvedFromPeers removes the digits of all squares that are solved in
+// values from their peers, without any further propagation. It returns false
+// if this results in a contradiction.
+func removeSolvedFromPeers(values Values) bool {
+	var solved []Index
+	for sq, d := range values {
+		if d.Size() == 1 {
+			solved = append(solved, sq)
+		}
+	}
+	for _, sq := range solved {
+		if values[sq].Size() != 1 {
+			return false
+		}
+		digit := values[sq].SingleMemberDigit()
+		for _, peer := range peers[sq] {
+			values[peer] = values[peer].Remove(digit)
+			if values[peer] == 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// squareName returns the conventional rXcY name of sq, with 1-based rows and
+// columns.
+func squareName(sq Index) string {
+	return fmt.Sprintf("r%dc%d", sq/9+1, sq%9+1)
+}
+
+// squareNames returns the names of all squares in sqs, separated by commas.
+func squareNames(sqs []Index) string {
+	names := make([]string, len(sqs))
+	for i, sq := range sqs {
+		names[i] = squareName(sq)
+	}
+	return strings.Join(names, ",")
+}
+
+// unitName returns a human-readable name of the unit at index u in unitlist.
+func unitName(u int) string {
+	switch {
+	case u < 9:
+		return fmt.Sprintf("row %d", u+1)
+	case u < 18:
+		return fmt.Sprintf("column %d", u-9+1)
+	default:
+		return fmt.Sprintf("box %d", u-18+1)
+	}
+}
+
+// unitNameOf returns a human-readable name of unit.
+func unitNameOf(unit Unit) string {
+	switch {
+	case rowOf(unit[0]) == rowOf(unit[8]):
+		return unitName(rowOf(unit[0]))
+	case colOf(unit[0]) == colOf(unit[8]):
+		return unitName(colOf(unit[0]))
+	default:
+		return unitName(boxOf(unit[0]))
+	}
+}
+
+// describe builds the Description of step from a technique-specific prefix
+// and the step's placements and eliminations.
+func describe(step *Step, format string, args ...any) *Step {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s: ", step.Technique)
+	fmt.Fprintf(&sb, format, args...)
+
+	var results []string
+	for _, p := range step.Placements {
+		results = append(results, p.String())
+	}
+	for _, e := range step.Eliminations {
+		results = append(results, fmt.Sprintf("%s<>%d", squareName(e.Square), e.Digit))
+	}
+	if len(results) > 0 {
+		fmt.Fprintf(&sb, " => %s", strings.Join(results, ", "))
+	}
+	step.Description = sb.String()
+	return step
+}
+
+// rowOf, colOf and boxOf return the index in unitlist of the row, column and
+// box unit of sq, respectively.
+func rowOf(sq Index) int { return sq / 9 }
+func colOf(sq Index) int { return 9 + sq%9 }
+func boxOf(sq Index) int { return 18 + (sq/27)*3 + (sq%9)/3 }
+
+// sees reports whether squares a and b are distinct peers.
+func sees(a, b Index) bool {
+	return a != b && (rowOf(a) == rowOf(b) || colOf(a) == colOf(b) || boxOf(a) == boxOf(b))
+}
+
+// seesAll reports whether sq is a peer of all the squares in sqs.
+func seesAll(sq Index, sqs []Index) bool {
+	for _, s := range sqs {
+		if !sees(sq, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// eliminationsSeeingAll collects the candidates for digit in squares that are
+// peers of all the squares in sqs.
+func eliminationsSeeingAll(values Values, digit uint16, sqs []Index) []Candidate {
+	var elims []Candidate
+	for sq := range values {
+		if values[sq].Size() > 1 && values[sq].IsMember(digit) && seesAll(sq, sqs) {
+			elims = append(elims, Candidate{sq, digit})
+		}
+	}
+	return elims
+}
+
+// forEachCombination calls f with each k-element combination of items, in
+// lexicographic order. It stops and returns true as soon as f returns true.
+// The slice passed to f is reused between calls.
+func forEachCombination(items []int, k int, f func(combo []int) bool) bool {
+	combo := make([]int, k)
+	var rec func(start, depth int) bool
+	rec = func(start, depth int) bool {
+		if depth == k {
+			return f(combo)
+		}
+		for i := start; i <= len(items)-(k-depth); i++ {
+			combo[depth] = items[i]
+			if rec(i+1, depth+1) {
+				return true
+			}
+		}
+		return false
+	}
+	return rec(0, 0)
+}
+
+// digitsOf returns the members of d in increasing order.
+func digitsOf(d Digits) []uint16 {
+	var ds []uint16
+	for n := uint16(1); n <= 9; n++ {
+		if d.IsMember(n) {
+			ds = append(ds, n)
+		}
+	}
+	return ds
+}
+
+// findNakedSingle finds a square with a single candidate digit that wasn't
+// placed yet. If placed is nil, squares with a single candidate are considered
+// placed unless the digit still appears among the candidates of their peers.
+func findNakedSingle(values Values, placed *[81]bool) *Step {
+	for sq, d := range values {
+		if d.Size() != 1 {
+			continue
+		}
+		digit := d.SingleMemberDigit()
+		if placed != nil && placed[sq] {
+			continue
+		}
+		if placed == nil && !slices.ContainsFunc(peers[sq], func(peer Index) bool {
+			return values[peer].IsMember(digit)
+		}) {
+			continue
+		}
+		step := &Step{
+			Technique:  NakedSingle,
+			Placements: []Candidate{{sq, digit}},
+			Cells:      []Index{sq},
+			Digits:     d,
+		}
+		return describe(step, "%s has a single candidate", squareName(sq))
+	}
+	return nil
+}
+
+// findHiddenSingle finds a digit that has only one possible square in some
+// unit.
+func findHiddenSingle(values Values) *Step {
+	for u, unit := range unitlist {
+		for digit := uint16(1); digit <= 9; digit++ {
+			place := -1
+			count := 0
+			for _, sq := range unit {
+				if values[sq].IsMember(digit) {
+					place = sq
+					count++
+				}
+			}
+			if count == 1 && values[place].Size() > 1 {
+				step := &Step{
+					Technique:  HiddenSingle,
+					Placements: []Candidate{{place, digit}},
+					Cells:      []Index{place},
+					Digits:     SingleDigitSet(digit),
+				}
+				return describe(step, "%d can only go in %s in %s", digit, squareName(place), unitName(u))
+			}
+		}
+	}
+	return nil
+}
+
+// findLockedCandidates finds a digit whose candidates in one unit are all
+// confined to the intersection with another unit, so it can be eliminated from
+// the rest of the other unit. This covers both "pointing" (box -> line) and
+// "claiming" (line -> box).
+func findLockedCandidates(values Values) *Step {
+	for u, unit := range unitlist {
+		for digit := uint16(1); digit <= 9; digit++ {
+			var sqs []Index
+			for _, sq := range unit {
+				if values[sq].Size() > 1 && values[sq].IsMember(digit) {
+					sqs = append(sqs, sq)
+				}
+			}
+			if len(sqs) < 2 {
+				continue
+			}
+
+			// Find the other units shared by all the squares.
+			for _, other := range units[sqs[0]] {
+				if slices.Equal(other, unit) || !containsAll(other, sqs) {
+					continue
+				}
+				var elims []Candidate
+				for _, sq := range other {
+					if values[sq].Size() > 1 && values[sq].IsMember(digit) && !slices.Contains(unit, sq) {
+						elims = append(elims, Candidate{sq, digit})
+					}
+				}
+				if len(elims) > 0 {
+					step := &Step{
+						Technique:    LockedCandidates,
+						Eliminations: elims,
+						Cells:        sqs,
+						Digits:       SingleDigitSet(digit),
+					}
+					return describe(step, "%d in %s is locked in %s", digit, unitName(u), squareNames(sqs))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// containsAll reports whether all of sqs are members of unit.
+func containsAll(unit Unit, sqs []Index) bool {
+	for _, sq := range sqs {
+		if !slices.Contains(unit, sq) {
+			return false
+		}
+	}
+	return true
+}
+
+var nakedSubsetTechniques = map[int]Technique{2: NakedPair, 3: NakedTriple, 4: NakedQuad}
+var hiddenSubsetTechniques = map[int]Technique{2: HiddenPair, 3: HiddenTriple, 4: HiddenQuad}
+
+// findNakedSubset returns a strategy that finds n squares in a unit whose
+// candidates are limited to the same n digits; these digits can be eliminated
+// from all other squares in the unit. ApplyTwinsStrategy is the n=2 case of
+// this strategy, with propagation.
+func findNakedSubset(n int) strategy {
+	return func(values Values) *Step {
+		for u, unit := range unitlist {
+			var open []int
+			for _, sq := range unit {
+				if values[sq].Size() > 1 && values[sq].Size() <= n {
+					open = append(open, sq)
+				}
+			}
+
+			var step *Step
+			forEachCombination(open, n, func(combo []int) bool {
+				var union Digits
+				for _, sq := range combo {
+					union |= values[sq]
+				}
+				if union.Size() != n {
+					return false
+				}
+
+				var elims []Candidate
+				for _, sq := range unit {
+					if values[sq].Size() > 1 && !slices.Contains(combo, sq) {
+						for _, digit := range digitsOf(values[sq] & union) {
+							elims = append(elims, Candidate{sq, digit})
+						}
+					}
+				}
+				if len(elims) == 0 {
+					return false
+				}
+				step = &Step{
+					Technique:    nakedSubsetTechniques[n],
+					Eliminations: elims,
+					Cells:        slices.Clone(combo),
+					Digits:       union,
+				}
+				describe(step, "%s in %s contain only %s", squareNames(combo), unitName(u), union)
+				return true
+			})
+			if step != nil {
+				return step
+			}
+		}
+		return nil
+	}
+}
+
+// findHiddenSubset returns a strategy that finds n digits confined to the same
+// n squares of a unit; all other candidates can be eliminated from these
+// squares.
+func findHiddenSubset(n int) strategy {
+	return func(values Values) *Step {
+		for u, unit := range unitlist {
+			// Map each unsolved digit to the set of its positions in the unit,
+			// encoded as a bitmask of offsets.
+			var positions [10]uint16
+			var open []int
+			for digit := uint16(1); digit <= 9; digit++ {
+				for i, sq := range unit {
+					if values[sq].IsMember(digit) {
+						if values[sq].Size() == 1 {
+							positions[digit] = 0
+							break
+						}
+						positions[digit] |= 1 << i
+					}
+				}
+				if c := Digits(positions[digit]).Size(); c >= 2 && c <= n {
+					open = append(open, int(digit))
+				}
+			}
+
+			var step *Step
+			forEachCombination(open, n, func(combo []int) bool {
+				var posUnion uint16
+				var digits Digits
+				for _, digit := range combo {
+					posUnion |= positions[digit]
+					digits = digits.Add(uint16(digit))
+				}
+				if Digits(posUnion).Size() != n {
+					return false
+				}
+
+				var cells []Index
+				var elims []Candidate
+				for i, sq := range unit {
+					if posUnion&(1<<i) != 0 {
+						cells = append(cells, sq)
+						for _, digit := range digitsOf(values[sq].RemoveAll(digits)) {
+							elims = append(elims, Candidate{sq, digit})
+						}
+					}
+				}
+				if len(elims) == 0 {
+					return false
+				}
+				step = &Step{
+					Technique:    hiddenSubsetTechniques[n],
+					Eliminations: elims,
+					Cells:        cells,
+					Digits:       digits,
+				}
+				describe(step, "%s in %s are confined to %s", digits, unitName(u), squareNames(cells))
+				return true
+			})
+			if step != nil {
+				return step
+			}
+		}
+		return nil
+	}
+}
+
+var fishTechniques = map[int]Technique{2: XWing, 3: Swordfish, 4: Jellyfish}
+
+// findFish returns a strategy for basic fish of size n (X-Wing, Swordfish and
+// Jellyfish): if a digit's candidates in n rows are confined to n columns, the
+// digit can be eliminated from these columns in all other rows (and likewise
+// with rows and columns swapped).
+func findFish(n int) strategy {
+	return func(values Values) *Step {
+		for digit := uint16(1); digit <= 9; digit++ {
+			for _, rowBased := range []bool{true, false} {
+				// cover[line] is a bitmask of the cross-line offsets where digit is
+				// a candidate in base line number 'line'.
+				var cover [9]uint16
+				var open []int
+				for line := 0; line < 9; line++ {
+					solved := false
+					for i := 0; i < 9; i++ {
+						sq := line*9 + i
+						if !rowBased {
+							sq = i*9 + line
+						}
+						if values[sq].IsMember(digit) {
+							if values[sq].Size() == 1 {
+								solved = true
+							}
+							cover[line] |= 1 << i
+						}
+					}
+					if c := Digits(cover[line]).Size(); !solved && c >= 2 && c <= n {
+						open = append(open, line)
+					}
+				}
+
+				var step *Step
+				forEachCombination(open, n, func(combo []int) bool {
+					var union uint16
+					for _, line := range combo {
+						union |= cover[line]
+					}
+					if Digits(union).Size() != n {
+						return false
+					}
+
+					var cells []Index
+					var elims []Candidate
+					for line := 0; line < 9; line++ {
+						base := slices.Contains(combo, line)
+						for i := 0; i < 9; i++ {
+							if union&(1<<i) == 0 {
+								continue
+							}
+							sq := line*9 + i
+							if !rowBased {
+								sq = i*9 + line
+							}
+							if !values[sq].IsMember(digit) {
+								continue
+							}
+							if base {
+								cells = append(cells, sq)
+							} else {
+								elims = append(elims, Candidate{sq, digit})
+							}
+						}
+					}
+					if len(elims) == 0 {
+						return false
+					}
+					slices.Sort(cells)
+					step = &Step{
+						Technique:    fishTechniques[n],
+						Eliminations: elims,
+						Cells:        cells,
+						Digits:       SingleDigitSet(digit),
+					}
+					lines := "rows"
+					if !rowBased {
+						lines = "columns"
+					}
+					describe(step, "%d in %s %v covers %s", digit, lines, oneBased(combo), squareNames(cells))
+					return true
+				})
+				if step != nil {
+					return step
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// oneBased returns a copy of the 0-based line numbers in lines, converted to
+// 1-based numbering for display.
+func oneBased(lines []int) []int {
+	r := make([]int, len(lines))
+	for i, l := range lines {
+		r[i] = l + 1
+	}
+	return r
+}
@@ -0,0 +1,247 @@
+package sudoku
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+// readInputBoards reads boards from a file in the inputs directory, one per
+// line, ignoring comments and empty lines.
+func readInputBoards(t *testing.T, filename string) []string {
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var boards []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		board := strings.TrimSpace(scanner.Text())
+		if len(board) > 0 && !strings.HasPrefix(board, "#") {
+			boards = append(boards, board)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return boards
+}
+
+// checkStepsAgainstSolution verifies that all the placements of steps agree
+// with solution, and that none of the eliminations removes a solution digit.
+func checkStepsAgainstSolution(t *testing.T, steps []Step, solution Values) {
+	t.Helper()
+	for _, step := range steps {
+		for _, p := range step.Placements {
+			if !solution[p.Square].IsMember(p.Digit) {
+				t.Errorf("wrong placement in step %q", step.Description)
+			}
+		}
+		for _, e := range step.Eliminations {
+			if solution[e.Square].IsMember(e.Digit) {
+				t.Errorf("wrong elimination in step %q", step.Description)
+			}
+		}
+	}
+}
+
+func TestSolveLogicallyEasy(t *testing.T) {
+	v, err := ParseBoard(easyboard1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vcopy := slices.Clone(v)
+
+	vs, steps, solved := SolveLogically(v)
+	if !slices.Equal(v, vcopy) {
+		t.Errorf("SolveLogically modified its input values")
+	}
+	if !solved || !IsSolved(vs) {
+		t.Errorf("expect easy board to be solved logically")
+	}
+
+	// The easy board is solved by propagation, so singles should suffice.
+	for _, step := range steps {
+		if step.Technique != NakedSingle && step.Technique != HiddenSingle {
+			t.Errorf("got step %q, want only singles", step.Description)
+		}
+	}
+	if len(steps) != 81-CountHints(v) {
+		t.Errorf("got %v steps, want %v", len(steps), 81-CountHints(v))
+	}
+}
+
+func TestSolveLogicallyInputs(t *testing.T) {
+	for _, filename := range []string{"inputs/norvig-easy50.txt", "inputs/norvig-hard.txt"} {
+		boards := readInputBoards(t, filename)
+		if testing.Short() && len(boards) > 20 {
+			boards = boards[:20]
+		}
+		for _, board := range boards {
+			v, err := ParseBoard(board, true)
+			if err != nil {
+				log.Fatal(err)
+			}
+			solution, ok := Solve(v)
+			if !ok {
+				t.Fatalf("cannot solve %v", board)
+			}
+
+			for _, assumeUnique := range []bool{false, true} {
+				_, steps, solved := SolveLogically(v, LogicOptions{AssumeUnique: assumeUnique})
+				checkStepsAgainstSolution(t, steps, solution)
+
+				if strings.Contains(filename, "easy") && !solved {
+					t.Errorf("expect easy board %v to be solved logically", board)
+				}
+				for _, step := range steps {
+					if !assumeUnique && step.Technique.RequiresUniqueness() {
+						t.Errorf("got step %q without AssumeUnique", step.Description)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestParseTechnique(t *testing.T) {
+	for tech := range techniqueNames {
+		got, err := ParseTechnique(tech.String())
+		if err != nil || got != tech {
+			t.Errorf("ParseTechnique(%q) = %v, %v", tech.String(), got, err)
+		}
+	}
+	if got, err := ParseTechnique("xwing"); err != nil || got != XWing {
+		t.Errorf("ParseTechnique(xwing) = %v, %v", got, err)
+	}
+	if _, err := ParseTechnique("guessing"); err == nil {
+		t.Errorf("got no error for unknown technique")
+	}
+}
+
+func TestSolveLogicallyMaxRating(t *testing.T) {
+	boards := readInputBoards(t, "inputs/norvig-hard.txt")[:10]
+	for _, board := range boards {
+		v, err := ParseBoard(board, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, max := range []float64{2.3, 3.4, 5.6} {
+			_, steps, _ := SolveLogically(v, LogicOptions{AssumeUnique: true, MaxRating: max})
+			for _, step := range steps {
+				if step.Technique.Rating() > max {
+					t.Errorf("got %v with MaxRating %v", step.Technique, max)
+				}
+			}
+		}
+	}
+}
+
+func TestNextStepSingles(t *testing.T) {
+	v := EmptyBoard()
+	v[0] = SingleDigitSet(5)
+	step, ok := NextStep(v)
+	if !ok || step.Technique != NakedSingle {
+		t.Fatalf("got step %v, want naked single", step)
+	}
+	if !slices.Equal(step.Placements, []Candidate{{0, 5}}) {
+		t.Errorf("got placements %v", step.Placements)
+	}
+
+	if !ApplyStep(v, step) {
+		t.Fatal("unexpected contradiction")
+	}
+	for _, peer := range peers[0] {
+		if v[peer].IsMember(5) {
+			t.Errorf("got 5 in peer %v after placement", peer)
+		}
+	}
+
+	// Remove 7 from all of row 2 except square 20; 7 is a hidden single there.
+	for sq := 18; sq < 27; sq++ {
+		if sq != 20 {
+			v[sq] = v[sq].Remove(7)
+		}
+	}
+	step, ok = NextStep(v)
+	if !ok || step.Technique != HiddenSingle || !slices.Equal(step.Placements, []Candidate{{20, 7}}) {
+		t.Errorf("got step %v, want hidden single r3c3=7", step)
+	}
+}
+
+func TestLockedCandidates(t *testing.T) {
+	// In box 1, 4 can only be in row 1; it can be eliminated from the rest of
+	// row 1.
+	v := EmptyBoard()
+	for _, sq := range []Index{0, 1, 2, 9, 10, 11} {
+		v[sq] = v[sq].Remove(4)
+	}
+	step := findLockedCandidates(v)
+	if step == nil {
+		t.Fatal("got no step")
+	}
+	if !slices.Equal(step.Cells, []Index{18, 19, 20}) {
+		t.Errorf("got cells %v", step.Cells)
+	}
+	for _, e := range step.Eliminations {
+		if e.Digit != 4 || e.Square < 21 || e.Square > 26 {
+			t.Errorf("got elimination %v", e)
+		}
+	}
+}
+
+func TestNakedAndHiddenSubsets(t *testing.T) {
+	v := EmptyBoard()
+	d38 := Digits(0).Add(3).Add(8)
+	v[30] = d38
+	v[31] = d38
+
+	step := findNakedSubset(2)(v)
+	if step == nil || step.Technique != NakedPair || step.Digits != d38 {
+		t.Fatalf("got step %v, want naked pair", step)
+	}
+	if !slices.Equal(step.Cells, []Index{30, 31}) {
+		t.Errorf("got cells %v", step.Cells)
+	}
+
+	// 1 and 2 only appear in squares 0 and 1 in row 1.
+	v = EmptyBoard()
+	for sq := 2; sq < 9; sq++ {
+		v[sq] = v[sq].Remove(1).Remove(2)
+	}
+	step = findHiddenSubset(2)(v)
+	if step == nil || step.Technique != HiddenPair {
+		t.Fatalf("got step %v, want hidden pair", step)
+	}
+	if len(step.Eliminations) != 14 {
+		t.Errorf("got %v eliminations, want 14", len(step.Eliminations))
+	}
+}
+
+func TestXWing(t *testing.T) {
+	// 6 appears in rows 1 and 5 only in columns 2 and 7.
+	v := EmptyBoard()
+	for _, row := range []int{0, 4} {
+		for col := 0; col < 9; col++ {
+			if col != 1 && col != 6 {
+				v[row*9+col] = v[row*9+col].Remove(6)
+			}
+		}
+	}
+	step := findFish(2)(v)
+	if step == nil || step.Technique != XWing {
+		t.Fatalf("got step %v, want X-Wing", step)
+	}
+	if !slices.Equal(step.Cells, []Index{1, 6, 37, 42}) {
+		t.Errorf("got cells %v", step.Cells)
+	}
+	if len(step.Eliminations) != 14 {
+		t.Errorf("got %v eliminations, want 14", len(step.Eliminations))
+	}
+}
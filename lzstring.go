@@ -0,0 +1,213 @@
+package sudoku
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/exp/slices"
+)
+
+// This file implements the compressToBase64 and decompressFromBase64
+// functions of the LZ-string JavaScript library, which f-puzzles and SudokuPad
+// use to encode puzzles in links. LZ-string works on UTF-16 code units, like
+// JavaScript strings.
+
+const lzBase64Alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/="
+
+// lzBitWriter writes values bit by bit into base64 characters, as LZ-string
+// does: the bits of each value from least significant, and each character from
+// its most significant bit.
+type lzBitWriter struct {
+	sb       strings.Builder
+	val, pos int
+}
+
+func (w *lzBitWriter) writeBits(value, n int) {
+	for i := 0; i < n; i++ {
+		w.val = w.val<<1 | value&1
+		value >>= 1
+		if w.pos == 5 {
+			w.sb.WriteByte(lzBase64Alphabet[w.val])
+			w.pos, w.val = 0, 0
+		} else {
+			w.pos++
+		}
+	}
+}
+
+// lzKey returns a map key for a sequence of UTF-16 code units.
+func lzKey(units ...uint16) string {
+	b := make([]byte, 0, 2*len(units))
+	for _, u := range units {
+		b = append(b, byte(u>>8), byte(u))
+	}
+	return string(b)
+}
+
+// lzCompressToBase64 compresses s like LZString.compressToBase64.
+func lzCompressToBase64(s string) string {
+	dict := make(map[string]int)
+	toCreate := make(map[string]bool)
+	enlargeIn, dictSize, numBits := 2, 3, 2
+	var bw lzBitWriter
+
+	// The dictionary grows by one entry for each code written; when it reaches
+	// a power of two, codes take another bit.
+	enlarge := func() {
+		enlargeIn--
+		if enlargeIn == 0 {
+			enlargeIn = 1 << numBits
+			numBits++
+		}
+	}
+
+	// writeW writes the code for w: a literal code unit the first time it's
+	// seen, or its dictionary entry.
+	writeW := func(w string) {
+		if toCreate[w] {
+			c := int(w[0])<<8 | int(w[1])
+			if c < 256 {
+				bw.writeBits(0, numBits)
+				bw.writeBits(c, 8)
+			} else {
+				bw.writeBits(1, numBits)
+				bw.writeBits(c, 16)
+			}
+			enlarge()
+			delete(toCreate, w)
+		} else {
+			bw.writeBits(dict[w], numBits)
+		}
+		enlarge()
+	}
+
+	w := ""
+	for _, u := range utf16.Encode([]rune(s)) {
+		c := lzKey(u)
+		if _, ok := dict[c]; !ok {
+			dict[c] = dictSize
+			dictSize++
+			toCreate[c] = true
+		}
+		if _, ok := dict[w+c]; ok {
+			w += c
+			continue
+		}
+		writeW(w)
+		dict[w+c] = dictSize
+		dictSize++
+		w = c
+	}
+	if len(w) > 0 {
+		writeW(w)
+	}
+
+	// Mark the end of the stream, and flush the last character. Like LZ-string,
+	// this always writes one more character, even when the marker ends on a
+	// character boundary.
+	bw.writeBits(2, numBits)
+	for {
+		bw.writeBits(0, 1)
+		if bw.pos == 0 {
+			break
+		}
+	}
+
+	out := bw.sb.String()
+	if n := len(out) % 4; n != 0 {
+		out += strings.Repeat("=", 4-n)
+	}
+	return out
+}
+
+// lzDecompressFromBase64 decompresses s like LZString.decompressFromBase64.
+func lzDecompressFromBase64(s string) (string, error) {
+	errCorrupt := errors.New("corrupt LZ-string data")
+	if len(s) == 0 {
+		return "", errCorrupt
+	}
+
+	values := make([]int, len(s))
+	for i := range s {
+		values[i] = strings.IndexByte(lzBase64Alphabet, s[i])
+		if values[i] < 0 {
+			return "", errCorrupt
+		}
+	}
+	val, position, index := values[0], 32, 1
+	readBits := func(n int) int {
+		bits := 0
+		for i := 0; i < n; i++ {
+			if val&position != 0 {
+				bits |= 1 << i
+			}
+			position >>= 1
+			if position == 0 {
+				position = 32
+				val = 0
+				if index < len(values) {
+					val = values[index]
+				}
+				index++
+			}
+		}
+		return bits
+	}
+
+	// The first three dictionary entries stand for the codes of literals and
+	// the end of the stream.
+	dict := make([][]uint16, 3)
+	enlargeIn, numBits := 4, 3
+
+	var c int
+	switch readBits(2) {
+	case 0:
+		c = readBits(8)
+	case 1:
+		c = readBits(16)
+	default:
+		return "", nil
+	}
+	w := []uint16{uint16(c)}
+	dict = append(dict, w)
+	result := slices.Clone(w)
+
+	for {
+		if index > len(values) {
+			return "", errCorrupt
+		}
+		c = readBits(numBits)
+		switch c {
+		case 0, 1:
+			dict = append(dict, []uint16{uint16(readBits(8 << c))})
+			c = len(dict) - 1
+			enlargeIn--
+		case 2:
+			return string(utf16.Decode(result)), nil
+		}
+		if enlargeIn == 0 {
+			enlargeIn = 1 << numBits
+			numBits++
+		}
+
+		var entry []uint16
+		switch {
+		case c < len(dict):
+			entry = dict[c]
+		case c == len(dict):
+			entry = append(slices.Clone(w), w[0])
+		default:
+			return "", errCorrupt
+		}
+		result = append(result, entry...)
+
+		dict = append(dict, append(slices.Clone(w), entry[0]))
+		enlargeIn--
+		w = entry
+		if enlargeIn == 0 {
+			enlargeIn = 1 << numBits
+			numBits++
+		}
+	}
+}
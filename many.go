@@ -0,0 +1,155 @@
+package sudoku
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+)
+
+// GenerateManyOptions configures GenerateMany.
+type GenerateManyOptions struct {
+	// Workers is the number of goroutines generating puzzles; if zero,
+	// runtime.NumCPU() is used.
+	Workers int
+
+	// MinDifficulty and MaxDifficulty are the range of difficulties of the
+	// generated puzzles, as in GenerateWithDifficulty. If both are zero,
+	// puzzles of any difficulty are generated.
+	MinDifficulty, MaxDifficulty float64
+
+	// Generate configures the generation of each puzzle. Its Rand field is
+	// ignored: each worker has its own source of randomness, seeded from the
+	// rand package's default source. If Timeout is set, it applies to each
+	// puzzle, and puzzles that time out are skipped.
+	Generate GenerateOptions
+}
+
+// PuzzleStream is the stream of puzzles generated by GenerateMany.
+type PuzzleStream struct {
+	// C receives the puzzles; it's closed when generation stops.
+	C <-chan Puzzle
+
+	err error
+}
+
+// Err returns the error generation failed with, once C is closed; it's nil if
+// generation stopped after all the puzzles or because ctx was canceled.
+func (s *PuzzleStream) Err() error {
+	return s.err
+}
+
+// GenerateMany generates n distinct random Sudoku puzzles in parallel with
+// GenerateWithDifficulty, and sends each to the channel of the returned stream
+// with its difficulty as soon as it's found. Puzzles are distinct in that no
+// two of them are equivalent (see Equivalent). If n <= 0, puzzles are
+// generated until ctx is canceled.
+//
+// GenerateMany returns an error without starting any workers if opts are
+// invalid, e.g. for an invalid difficulty range or an unknown symmetry. The
+// channel is closed after n puzzles, when ctx is canceled, or if generation
+// still fails with an error other than a timeout, which the stream's Err then
+// returns. The caller must either read from the channel until it's closed or
+// cancel ctx, so that the workers exit. Stats must not be enabled while
+// GenerateMany runs, since the workers share them.
+func GenerateMany(ctx context.Context, n int, opts GenerateManyOptions) (*PuzzleStream, error) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	min, max := opts.MinDifficulty, opts.MaxDifficulty
+	genOpts := opts.Generate
+	if min == 0 && max == 0 {
+		min, max = 1.0, 5.0
+	}
+	if min > max {
+		return nil, fmt.Errorf("invalid difficulty range [%v, %v]", min, max)
+	}
+	if genOpts.Symmetry < SymmetryNone || genOpts.Symmetry > SymmetryDihedral {
+		return nil, fmt.Errorf("unknown symmetry %d", int(genOpts.Symmetry))
+	}
+	if genOpts.Difficulty.Mode < SearchRandom || genOpts.Difficulty.Mode > SearchExhaustive {
+		return nil, fmt.Errorf("unknown search mode %v", genOpts.Difficulty.Mode)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	genOpts.ctx = ctx
+
+	// Workers send each result with the key it's de-duplicated by: its
+	// canonical form.
+	type keyedResult struct {
+		GenerateResult
+		key string
+	}
+	results := make(chan keyedResult)
+
+	// The first error of a worker stops all of them.
+	var errOnce sync.Once
+	var workerErr error
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(wopts GenerateOptions) {
+			defer wg.Done()
+			wopts.Rand = rand.New(rand.NewSource(rand.Int63()))
+			for ctx.Err() == nil {
+				res, err := GenerateWithDifficulty(min, max, wopts)
+				if errors.Is(err, ErrTimeout) {
+					continue
+				} else if err != nil {
+					// Errors from the workers being stopped aren't failures.
+					if ctxErr := ctx.Err(); ctxErr == nil || !errors.Is(err, ctxErr) {
+						errOnce.Do(func() { workerErr = err })
+					}
+					cancel()
+					return
+				}
+				canon, _ := Canonicalize(res.Board)
+				select {
+				case results <- keyedResult{res, DisplayAsInput(canon)}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(genOpts)
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	out := make(chan Puzzle)
+	stream := &PuzzleStream{C: out}
+	go func() {
+		defer close(out)
+		defer cancel()
+
+		seen := make(map[string]bool)
+		count := 0
+		for res := range results {
+			if seen[res.key] {
+				continue
+			}
+			seen[res.key] = true
+
+			select {
+			case out <- Puzzle{Board: res.Board, Difficulty: res.Difficulty}:
+				count++
+			case <-ctx.Done():
+			}
+			if ctx.Err() != nil || (n > 0 && count >= n) {
+				break
+			}
+		}
+
+		// Stop the workers and let any of them blocked on sending finish.
+		cancel()
+		for range results {
+		}
+		stream.err = workerErr
+	}()
+	return stream, nil
+}
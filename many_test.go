@@ -0,0 +1,111 @@
+package sudoku
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGenerateMany(t *testing.T) {
+	opts := GenerateManyOptions{Workers: 4, MinDifficulty: 2.0, MaxDifficulty: 3.5}
+	seen := make(map[string]bool)
+	stream, err := GenerateMany(context.Background(), 6, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p := range stream.C {
+		if p.Difficulty < opts.MinDifficulty || p.Difficulty > opts.MaxDifficulty {
+			t.Errorf("got difficulty %v, want [%v, %v]", p.Difficulty, opts.MinDifficulty, opts.MaxDifficulty)
+		}
+		if n := len(SolveAll(p.Board, 2)); n != 1 {
+			t.Errorf("got %v solutions, want 1", n)
+		}
+		canon, _ := Canonicalize(p.Board)
+		key := DisplayAsInput(canon)
+		if seen[key] {
+			t.Errorf("got equivalent boards:\n%v", key)
+		}
+		seen[key] = true
+	}
+	if err := stream.Err(); err != nil {
+		t.Errorf("got err %v after generating puzzles", err)
+	}
+	if len(seen) != 6 {
+		t.Errorf("got %v puzzles, want 6", len(seen))
+	}
+}
+
+func TestGenerateManyAnyDifficulty(t *testing.T) {
+	stream, err := GenerateMany(context.Background(), 2, GenerateManyOptions{Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p := range stream.C {
+		if n := CountHints(p.Board); n > 40 {
+			t.Errorf("got board with %v hints:\n%v", n, DisplayAsInput(p.Board))
+		}
+	}
+
+	stream, err = GenerateMany(context.Background(), 2, GenerateManyOptions{Workers: 2, Generate: GenerateOptions{Minimal: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p := range stream.C {
+		if minimal, _, err := IsMinimal(p.Board); err != nil || !minimal {
+			t.Errorf("got non-minimal board, err %v", err)
+		}
+	}
+}
+
+func TestGenerateManyCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := GenerateMany(ctx, 0, GenerateManyOptions{Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-stream.C
+	cancel()
+
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case _, ok := <-stream.C:
+			if !ok {
+				if err := stream.Err(); err != nil {
+					t.Errorf("got err %v after cancel", err)
+				}
+				return
+			}
+		case <-timeout:
+			t.Fatal("channel not closed after cancel")
+		}
+	}
+}
+
+func TestGenerateManyErrors(t *testing.T) {
+	for _, opts := range []GenerateManyOptions{
+		{MinDifficulty: 3.0, MaxDifficulty: 2.0},
+		{Generate: GenerateOptions{Symmetry: Symmetry(100)}},
+		{Generate: GenerateOptions{Difficulty: DifficultyOptions{Mode: SearchMode(100)}}},
+	} {
+		if stream, err := GenerateMany(context.Background(), 5, opts); err == nil || stream != nil {
+			t.Errorf("got no error for options %+v", opts)
+		}
+	}
+}
+
+func TestGenerateManyWorkerError(t *testing.T) {
+	// Negative iterations are only rejected when evaluating a board.
+	opts := GenerateManyOptions{Workers: 2, Generate: GenerateOptions{Difficulty: DifficultyOptions{Iterations: -1}}}
+	stream, err := GenerateMany(context.Background(), 5, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for range stream.C {
+		n++
+	}
+	if n != 0 || stream.Err() == nil {
+		t.Errorf("got %v puzzles and err %v, want no puzzles and an error", n, stream.Err())
+	}
+}
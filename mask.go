@@ -0,0 +1,187 @@
+package sudoku
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+// ParseMask parses a mask of clue squares from str: a sequence of 81 runes,
+// where any of [123456789xX#*] marks a clue square and 0 or . marks an empty
+// square. All other runes are ignored, like in ParseBoard; in particular, a
+// board can be used as the mask of its own clues.
+func ParseMask(str string) ([81]bool, error) {
+	var mask [81]bool
+	n := 0
+	for _, r := range str {
+		var clue bool
+		switch {
+		case r >= '1' && r <= '9' || strings.ContainsRune("xX#*", r):
+			clue = true
+		case r == '0' || r == '.':
+			clue = false
+		default:
+			continue
+		}
+		if n < 81 {
+			mask[n] = clue
+		}
+		n++
+	}
+
+	if n != 81 {
+		return mask, fmt.Errorf("got %v squares in mask, want 81", n)
+	}
+	return mask, nil
+}
+
+// maskMinClues is the minimal number of clues of a Sudoku puzzle with a single
+// solution.
+const maskMinClues = 17
+
+// maskSolutionsCap caps the number of solutions GenerateFromMask counts for
+// the initial puzzle of each restart.
+const maskSolutionsCap = 10000
+
+// maskMovesPerRestart is the number of clue changes GenerateFromMask tries
+// before starting over with a new solved board.
+const maskMovesPerRestart = 500
+
+// maskTargetedSolutions is the number of solutions below which GenerateFromMask
+// targets its clue changes at the differences between the solutions.
+const maskTargetedSolutions = 10
+
+// GenerateFromMask generates a random Sudoku puzzle whose clues are exactly
+// the squares marked in mask, with a single solution.
+//
+// It starts from a random solved board and keeps its digits in the masked
+// squares as clues. While the puzzle has more than one solution, it changes
+// the digit of a random clue, keeping changes that don't increase the number
+// of solutions; if that doesn't lead to a unique puzzle after a while, it
+// starts over with a new solved board. Only the Timeout, Difficulty and Rand
+// fields of opts are used; Tried in the result counts the candidate puzzles
+// checked.
+//
+// It returns an error if opts.Timeout passes before a puzzle is found, or if
+// the mask has fewer than 17 clues, since such puzzles can't have a single
+// solution. Masks with few clues or with large empty areas may take a long
+// time or never succeed.
+func GenerateFromMask(mask [81]bool, opts GenerateOptions) (GenerateResult, error) {
+	var clueSquares []Index
+	for sq, clue := range mask {
+		if clue {
+			clueSquares = append(clueSquares, sq)
+		}
+	}
+	if len(clueSquares) < maskMinClues {
+		return GenerateResult{}, fmt.Errorf("got %v clues in mask, want at least %v", len(clueSquares), maskMinClues)
+	}
+
+	rng := opts.Rand
+	if rng == nil {
+		rng = rand.New(rand.NewSource(rand.Int63()))
+	}
+	start := time.Now()
+
+	var result GenerateResult
+
+	// countSolutions counts the solutions of puzzle, up to max.
+	countSolutions := func(puzzle Values, max int) int {
+		result.Tried++
+		vcopy := slices.Clone(puzzle)
+		if !EliminateAll(vcopy) {
+			return 0
+		}
+		_, n := countSearchTree(vcopy, max)
+		return n
+	}
+
+	for {
+		grid, solved := Solve(EmptyBoard(), SolveOptions{Randomize: true, Rand: rng})
+		if !solved || !IsSolved(grid) {
+			return result, fmt.Errorf("unable to generate solved board from empty")
+		}
+		puzzle := EmptyBoard()
+		for _, sq := range clueSquares {
+			puzzle[sq] = grid[sq]
+		}
+		count := countSolutions(puzzle, maskSolutionsCap)
+
+		for move := 0; move < maskMovesPerRestart && count > 1; move++ {
+			if opts.Timeout > 0 && time.Since(start) > opts.Timeout {
+				return result, ErrTimeout
+			}
+
+			// Change a random clue to a digit that doesn't conflict with the
+			// clues among its peers. With few solutions left, prefer clues
+			// that see the squares where the solutions differ.
+			sq := clueSquares[rng.Intn(len(clueSquares))]
+			if count <= maskTargetedSolutions && rng.Intn(2) == 0 {
+				if targets := cluesSeeingDifferences(puzzle, mask); len(targets) > 0 {
+					sq = targets[rng.Intn(len(targets))]
+				}
+			}
+			allowed := FullDigitsSet().Remove(puzzle[sq].SingleMemberDigit())
+			for _, peer := range peers[sq] {
+				if mask[peer] {
+					allowed = allowed.RemoveAll(puzzle[peer])
+				}
+			}
+			digits := digitsOf(allowed)
+			if len(digits) == 0 {
+				continue
+			}
+
+			saved := puzzle[sq]
+			puzzle[sq] = SingleDigitSet(digits[rng.Intn(len(digits))])
+			// Only changes that don't increase the count are kept, so there's
+			// no need to count further.
+			if n := countSolutions(puzzle, count+1); n > 0 && n <= count {
+				count = n
+			} else {
+				puzzle[sq] = saved
+			}
+		}
+
+		if count == 1 {
+			report, err := EvaluateDifficultyReport(puzzle, opts.Difficulty)
+			if err != nil {
+				return result, err
+			}
+			result.Board = puzzle
+			result.Difficulty = report.Score
+			return result, nil
+		}
+	}
+}
+
+// cluesSeeingDifferences finds the squares where the solutions of puzzle
+// differ, and returns the clues in mask that are peers of any of them.
+func cluesSeeingDifferences(puzzle Values, mask [81]bool) []Index {
+	vcopy := slices.Clone(puzzle)
+	if !EliminateAll(vcopy) {
+		return nil
+	}
+	solutions := SolveAll(vcopy, maskTargetedSolutions)
+
+	var seeing squareSet
+	for sq := range puzzle {
+		for _, s := range solutions[1:] {
+			if s[sq] != solutions[0][sq] {
+				seeing = seeing.or(peerSets[sq])
+				break
+			}
+		}
+	}
+
+	var clues []Index
+	for _, sq := range seeing.squares() {
+		if mask[sq] {
+			clues = append(clues, sq)
+		}
+	}
+	return clues
+}
@@ -0,0 +1,98 @@
+package sudoku
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+var heartMask = `
+. X X . . . X X .
+X . . X . X . . X
+X . . . X . . . X
+X . . . . . . . X
+. X . . . . . X .
+. . X . . . X . .
+. X . X . X . X .
+X . . . X . . . X
+. X X . . . X X .`
+
+func TestParseMask(t *testing.T) {
+	mask, err := ParseMask(heartMask)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !mask[1] || mask[0] || !mask[80-1] {
+		t.Errorf("got wrong mask %v", mask)
+	}
+
+	// A board is the mask of its own clues.
+	v, err := ParseBoard(hardboard1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mask, err = ParseMask(hardboard1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for sq, clue := range mask {
+		if clue != (v[sq].Size() == 1) {
+			t.Errorf("got mask %v for square %v, want %v", clue, sq, v[sq].Size() == 1)
+		}
+	}
+
+	if _, err := ParseMask("xx.."); err == nil {
+		t.Errorf("got no error for short mask")
+	}
+}
+
+func TestGenerateFromMask(t *testing.T) {
+	heart, err := ParseMask(heartMask)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := rand.New(rand.NewSource(1))
+	var random [81]bool
+	for _, sq := range rng.Perm(81)[:24] {
+		random[sq] = true
+	}
+
+	for _, mask := range [][81]bool{heart, random} {
+		res, err := GenerateFromMask(mask, GenerateOptions{Rand: rng})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for sq, clue := range mask {
+			if clue != (res.Board[sq].Size() == 1) {
+				t.Errorf("got square %v with %v, want clue=%v", sq, res.Board[sq], clue)
+			}
+		}
+		if n := len(SolveAll(res.Board, 2)); n != 1 {
+			t.Errorf("got %v solutions, want 1", n)
+		}
+		if res.Tried < 1 || res.Difficulty < 1.0 {
+			t.Errorf("got Tried=%v, Difficulty=%v", res.Tried, res.Difficulty)
+		}
+	}
+}
+
+func TestGenerateFromMaskErrors(t *testing.T) {
+	var mask [81]bool
+	for sq := 0; sq < 16; sq++ {
+		mask[sq] = true
+	}
+	if _, err := GenerateFromMask(mask, GenerateOptions{}); err == nil {
+		t.Errorf("got no error for mask with 16 clues")
+	}
+
+	// With the bottom six rows empty, rows can be swapped, so there's always
+	// more than one solution.
+	for sq := 0; sq < 27; sq++ {
+		mask[sq] = true
+	}
+	_, err := GenerateFromMask(mask, GenerateOptions{Timeout: 100 * time.Millisecond})
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("got err %v, want ErrTimeout", err)
+	}
+}
@@ -0,0 +1,104 @@
+package sudoku
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Puzzle is a Sudoku puzzle together with information about it, and
+// optionally the state of solving it.
+//
+// Puzzles are encoded in JSON with a stable schema; all fields except version
+// and givens are omitted when empty, and boards are encoded as strings by
+// Values.MarshalText:
+//
+//	{
+//	  "version": 1,
+//	  "givens": "4.....8.5.3..........7......2.....6.....8.4......1.......6.3.7.5..2.....1.4......",
+//	  "candidates": "4 1679 12679 139 ...",
+//	  "entries": "..............................................................6..................",
+//	  "difficulty": 3.1,
+//	  "title": "...", "author": "...", "source": "...", "date": "...",
+//	  "url": "...", "level": "...", "comment": "...",
+//	  "constraints": [{"type": "killer", "squares": [0, 1, 9], "value": "12"}]
+//	}
+//
+// Decoding fails for versions newer than the current one (1).
+type Puzzle struct {
+	// Board holds the clues of the puzzle (the givens); other squares have all
+	// candidates.
+	Board Values `json:"givens"`
+
+	// Candidates holds the candidates of each square in the current state of
+	// solving the puzzle, if any.
+	Candidates Values `json:"candidates,omitempty"`
+
+	// Entries holds the digits entered by the solver in the current state of
+	// solving the puzzle, if any: squares with a single digit are entries.
+	Entries Values `json:"entries,omitempty"`
+
+	// Difficulty is the rating of the puzzle, as computed by
+	// EvaluateDifficultyReport; it's 0 if the puzzle wasn't rated.
+	Difficulty float64 `json:"difficulty,omitempty"`
+
+	// Metadata about the puzzle, as found in puzzle files; any of it may be
+	// empty. Level is the puzzle's difficulty in whatever form its source
+	// gives it, e.g. "Hard" or "7.2".
+	Title   string `json:"title,omitempty"`
+	Author  string `json:"author,omitempty"`
+	Source  string `json:"source,omitempty"`
+	Date    string `json:"date,omitempty"`
+	URL     string `json:"url,omitempty"`
+	Level   string `json:"level,omitempty"`
+	Comment string `json:"comment,omitempty"`
+
+	// Constraints are the additional constraints of Sudoku variants.
+	Constraints []Constraint `json:"constraints,omitempty"`
+}
+
+// Constraint is an additional constraint of a Sudoku variant, such as a
+// killer cage or a thermometer. The solvers in this package don't support
+// variants; constraints are kept so that puzzles pass through unchanged.
+type Constraint struct {
+	// Type is the kind of constraint, e.g. "killer" or "thermo".
+	Type string `json:"type"`
+
+	// Squares are the squares the constraint applies to, in an order that
+	// depends on Type.
+	Squares []Index `json:"squares"`
+
+	// Value is the parameter of the constraint, if any, e.g. the sum of a
+	// killer cage.
+	Value string `json:"value,omitempty"`
+}
+
+// puzzleVersion is the version of the JSON schema of Puzzle.
+const puzzleVersion = 1
+
+// puzzleFields has the fields of Puzzle without its methods, for encoding
+// them with the default encoding.
+type puzzleFields Puzzle
+
+// MarshalJSON implements the json.Marshaler interface for Puzzle.
+func (p Puzzle) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Version int `json:"version"`
+		puzzleFields
+	}{puzzleVersion, puzzleFields(p)})
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for Puzzle.
+func (p *Puzzle) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Version int `json:"version"`
+		puzzleFields
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.Version > puzzleVersion {
+		return fmt.Errorf("got puzzle version %v, want at most %v", v.Version, puzzleVersion)
+	}
+	*p = Puzzle(v.puzzleFields)
+	return nil
+}
@@ -0,0 +1,97 @@
+package sudoku
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestValuesMarshalText(t *testing.T) {
+	for _, eliminate := range []bool{false, true} {
+		v, err := ParseBoard(hardboard1, eliminate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, err := v.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if compact := !strings.Contains(string(text), " "); compact == eliminate {
+			t.Errorf("got compact=%v for eliminate=%v: %s", compact, eliminate, text)
+		}
+
+		var got Values
+		if err := got.UnmarshalText(text); err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, v) {
+			t.Errorf("got different board after round trip:\n%v", Display(got))
+		}
+	}
+
+	v, err := ParseBoard(hardboard1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"` + hardboard1 + `"`; string(text) != want {
+		t.Errorf("got JSON %s, want %s", text, want)
+	}
+
+	v[3] = 0
+	if _, err := v.MarshalText(); err == nil {
+		t.Errorf("got no error for square without candidates")
+	}
+	if _, err := Values(nil).MarshalText(); err == nil {
+		t.Errorf("got no error for empty board")
+	}
+}
+
+func TestPuzzleJSON(t *testing.T) {
+	board, err := ParseBoard(hardboard1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Puzzle{Board: board, Title: "hard", Difficulty: 3.5}
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"version":1,"givens":"` + hardboard1 + `","difficulty":3.5,"title":"hard"}`
+	if string(data) != want {
+		t.Errorf("got JSON %s, want %s", data, want)
+	}
+
+	// Round trip with the state of solving and constraints.
+	p.Candidates, err = ParseBoard(hardboard1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Entries = EmptyBoard()
+	p.Entries[1] = SingleDigitSet(6)
+	p.Constraints = []Constraint{{Type: "killer", Squares: []Index{1, 2}, Value: "13"}}
+	data, err = json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Puzzle
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, p) {
+		t.Errorf("got different puzzle after round trip:\n%+v\n%+v", got, p)
+	}
+
+	if err := json.Unmarshal([]byte(`{"version":2,"givens":"`+hardboard1+`"}`), &got); err == nil {
+		t.Errorf("got no error for newer version")
+	}
+	if err := json.Unmarshal([]byte(`{"version":1,"givens":"123"}`), &got); err == nil {
+		t.Errorf("got no error for invalid givens")
+	}
+}
@@ -0,0 +1,169 @@
+package sudoku
+
+import (
+	"fmt"
+
+	"golang.org/x/exp/slices"
+)
+
+// This file contains a technique-based difficulty rater, in the style of
+// Sudoku Explainer and HoDoKu: the puzzle is solved with the logical solver,
+// and its rating is the rating of the hardest technique required.
+
+// techniqueRatings maps each technique to its rating, on a scale similar to
+// the one used by Sudoku Explainer. Techniques that Sudoku Explainer doesn't
+// have are placed where their complexity fits.
+var techniqueRatings = map[Technique]float64{
+	HiddenSingle:      1.5,
+	NakedSingle:       2.3,
+	LockedCandidates:  2.6,
+	NakedPair:         3.0,
+	XWing:             3.2,
+	HiddenPair:        3.4,
+	NakedTriple:       3.6,
+	Swordfish:         3.8,
+	HiddenTriple:      4.0,
+	UniqueRectangle1:  4.5,
+	UniqueRectangle2:  4.5,
+	UniqueRectangle4:  4.5,
+	UniqueRectangle5:  4.6,
+	UniqueRectangle6:  4.6,
+	UniqueRectangle3:  4.7,
+	NakedQuad:         5.0,
+	Jellyfish:         5.2,
+	HiddenQuad:        5.4,
+	BUGPlusOne:        5.6,
+	ALSXZ:             6.2,
+	ALSXYWing:         6.6,
+	DeathBlossom:      7.0,
+	DigitForcingChain: 7.2,
+	CellForcingChain:  7.5,
+	UnitForcingChain:  7.8,
+	DigitForcingNet:   8.2,
+	CellForcingNet:    8.5,
+	UnitForcingNet:    8.8,
+}
+
+// unsolvedRating is the rating given to puzzles that the logical solver can't
+// solve.
+const unsolvedRating = 10.0
+
+// Rating returns the difficulty rating of technique t, on a scale from 1.0 to
+// 10.0 similar to the one used by Sudoku Explainer.
+func (t Technique) Rating() float64 {
+	return techniqueRatings[t]
+}
+
+// DifficultyTier is a named difficulty level.
+type DifficultyTier int
+
+const (
+	TierEasy DifficultyTier = iota
+	TierMedium
+	TierHard
+	TierExpert
+	TierExtreme
+)
+
+var tierNames = []string{"easy", "medium", "hard", "expert", "extreme"}
+
+// String implements the fmt.Stringer interface for DifficultyTier.
+func (t DifficultyTier) String() string {
+	if t >= 0 && int(t) < len(tierNames) {
+		return tierNames[t]
+	}
+	return fmt.Sprintf("DifficultyTier(%d)", int(t))
+}
+
+// tierMaxRatings holds the maximal rating of each tier except the last.
+var tierMaxRatings = []float64{2.3, 3.0, 4.7, 6.0}
+
+// TierForRating returns the difficulty tier of a puzzle with the given rating.
+// Easy puzzles only need singles; medium puzzles need locked candidates or
+// naked pairs; hard puzzles need subsets, basic fish or unique rectangles;
+// expert puzzles need quads, Jellyfish or BUG+1; extreme puzzles need ALS
+// techniques, forcing chains or worse.
+func TierForRating(rating float64) DifficultyTier {
+	for i, max := range tierMaxRatings {
+		if rating <= max {
+			return DifficultyTier(i)
+		}
+	}
+	return TierExtreme
+}
+
+// TechniqueRating is the result of EvaluateTechniqueDifficulty.
+type TechniqueRating struct {
+	// Rating is the rating of the hardest technique needed to solve the
+	// puzzle (see Technique.Rating).
+	Rating float64
+
+	// Tier is the named difficulty tier for Rating.
+	Tier DifficultyTier
+
+	// Hardest is the hardest technique needed to solve the puzzle.
+	Hardest Technique
+
+	// Histogram counts how many times each technique was used.
+	Histogram map[Technique]int
+
+	// Solved is false if the logical solver couldn't solve the puzzle; in this
+	// case Rating is 10.0 and Hardest is meaningless.
+	Solved bool
+
+	// Steps are the steps taken by the logical solver.
+	Steps []Step
+}
+
+// EvaluateTechniqueDifficulty rates the difficulty of a Sudoku puzzle by
+// solving it with the logical solver and finding the hardest technique it
+// requires, similarly to Sudoku Explainer. Unlike EvaluateDifficulty, this is
+// deterministic and reflects the techniques a human solver needs.
+// The puzzle must have a single solution, since uniqueness-based techniques
+// are used. It returns an error if the board has contradictions, has no
+// solution or has multiple solutions. values is not modified.
+func EvaluateTechniqueDifficulty(values Values) (TechniqueRating, error) {
+	if len(values) != 81 {
+		return TechniqueRating{}, fmt.Errorf("got board with %v squares, want 81", len(values))
+	}
+	vcopy := slices.Clone(values)
+	if !EliminateAll(vcopy) {
+		return TechniqueRating{}, fmt.Errorf("contradiction in board")
+	}
+	switch len(SolveAll(vcopy, 2)) {
+	case 0:
+		return TechniqueRating{}, fmt.Errorf("cannot solve")
+	case 1:
+	default:
+		return TechniqueRating{}, ErrMultipleSolutions
+	}
+
+	return rateTechniques(values, LogicOptions{AssumeUnique: true}), nil
+}
+
+// rateTechniques rates values, which must have a single solution, by solving
+// it logically with opts.
+func rateTechniques(values Values, opts LogicOptions) TechniqueRating {
+	_, steps, solved := SolveLogically(values, opts)
+	rating := TechniqueRating{
+		Histogram: make(map[Technique]int),
+		Solved:    solved,
+		Steps:     steps,
+	}
+	for _, step := range steps {
+		rating.Histogram[step.Technique]++
+		if step.Technique.Rating() > rating.Rating {
+			rating.Rating = step.Technique.Rating()
+			rating.Hardest = step.Technique
+		}
+	}
+	if !solved {
+		rating.Rating = unsolvedRating
+	}
+	if len(steps) == 0 {
+		// A board that's already filled in.
+		rating.Rating = 1.0
+	}
+	rating.Tier = TierForRating(rating.Rating)
+	return rating
+}
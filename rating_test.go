@@ -0,0 +1,146 @@
+package sudoku
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestTierForRating(t *testing.T) {
+	var tests = []struct {
+		rating float64
+		want   DifficultyTier
+	}{
+		{1.0, TierEasy},
+		{2.3, TierEasy},
+		{2.6, TierMedium},
+		{3.4, TierHard},
+		{4.5, TierHard},
+		{5.6, TierExpert},
+		{6.2, TierExtreme},
+		{10.0, TierExtreme},
+	}
+
+	for _, tt := range tests {
+		if got := TierForRating(tt.rating); got != tt.want {
+			t.Errorf("TierForRating(%v) = %v, want %v", tt.rating, got, tt.want)
+		}
+	}
+}
+
+func TestTechniqueRatings(t *testing.T) {
+	for tech := range techniqueNames {
+		if tech.Rating() == 0 {
+			t.Errorf("no rating for %v", tech)
+		}
+	}
+}
+
+func TestLogicStrategiesOrdered(t *testing.T) {
+	// nextStep relies on this order to skip strategies above MaxRating.
+	for i := 1; i < len(logicStrategies); i++ {
+		prev, cur := logicStrategies[i-1].technique, logicStrategies[i].technique
+		if cur.Rating() < prev.Rating() {
+			t.Errorf("strategy for %v is after %v, which is rated higher", cur, prev)
+		}
+	}
+}
+
+// rateInputs rates all the boards in an input file and returns a histogram of
+// tiers and the average rating.
+func rateInputs(t *testing.T, filename string) (map[DifficultyTier]int, float64) {
+	tiers := make(map[DifficultyTier]int)
+	var total float64
+	boards := readInputBoards(t, filename)
+	for _, board := range boards {
+		v, err := ParseBoard(board, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vcopy := slices.Clone(v)
+
+		r, err := EvaluateTechniqueDifficulty(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(v, vcopy) {
+			t.Errorf("EvaluateTechniqueDifficulty modified values")
+		}
+		if r.Tier != TierForRating(r.Rating) {
+			t.Errorf("got tier %v for rating %v", r.Tier, r.Rating)
+		}
+		if r.Solved && r.Rating != r.Hardest.Rating() {
+			t.Errorf("got rating %v, hardest technique %v", r.Rating, r.Hardest)
+		}
+		steps := 0
+		for _, count := range r.Histogram {
+			steps += count
+		}
+		if steps != len(r.Steps) {
+			t.Errorf("got histogram with %v steps, want %v", steps, len(r.Steps))
+		}
+
+		tiers[r.Tier]++
+		total += r.Rating
+	}
+	return tiers, total / float64(len(boards))
+}
+
+func TestEvaluateTechniqueDifficultyInputs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test in short mode.")
+	}
+
+	easyTiers, easyAverage := rateInputs(t, "inputs/norvig-easy50.txt")
+	hardTiers, hardAverage := rateInputs(t, "inputs/norvig-hard.txt")
+
+	// Most of the easy boards need only singles, and none of them needs
+	// advanced techniques.
+	if easyTiers[TierEasy] < 40 || easyTiers[TierExpert]+easyTiers[TierExtreme] > 0 {
+		t.Errorf("got easy tiers %v", easyTiers)
+	}
+	// Most of the hard boards are extreme.
+	if hardTiers[TierExtreme] < 60 {
+		t.Errorf("got hard tiers %v", hardTiers)
+	}
+	if easyAverage >= hardAverage {
+		t.Errorf("got easy average %v >= hard average %v", easyAverage, hardAverage)
+	}
+}
+
+func TestEvaluateTechniqueDifficulty(t *testing.T) {
+	rate := func(board string) TechniqueRating {
+		v, err := ParseBoard(board, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := EvaluateTechniqueDifficulty(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	if r := rate(filled); r.Rating != 1.0 || r.Tier != TierEasy {
+		t.Errorf("got rating %v for filled board", r.Rating)
+	}
+	if r := rate(easyboard1); r.Tier != TierEasy || !r.Solved {
+		t.Errorf("got %v for easy board", r.Tier)
+	}
+	if r := rate(hardboard1); r.Tier <= TierEasy {
+		t.Errorf("got %v for hard board", r.Tier)
+	}
+
+	v, err := ParseBoard("123456789"+strings.Repeat(".", 72), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := EvaluateTechniqueDifficulty(v); !errors.Is(err, ErrMultipleSolutions) {
+		t.Errorf("got err %v, want ErrMultipleSolutions", err)
+	}
+	if _, err := EvaluateTechniqueDifficulty(nil); err == nil {
+		t.Errorf("got no error for empty board")
+	}
+}
@@ -0,0 +1,420 @@
+// Package store implements an on-disk library of Sudoku puzzles.
+//
+// A store is a single append-only file of JSON records, one per line: either
+// a puzzle with information about it, or a mark that a puzzle was seen.
+// Puzzles are keyed by their canonical form (see sudoku.Canonicalize), so
+// equivalent puzzles are only stored once. The whole store is loaded into
+// memory when it's opened, and indexed by difficulty for queries.
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/eliben/go-sudoku"
+	"golang.org/x/exp/slices"
+)
+
+// Entry is a puzzle in the store.
+type Entry struct {
+	// Key is the canonical form of the puzzle, on a single line.
+	Key string `json:"key"`
+
+	// Board is the puzzle as it was added, on a single line.
+	Board string `json:"board"`
+
+	// Clues is the number of clues of the puzzle.
+	Clues int `json:"clues"`
+
+	// Symmetries are the names of the symmetries of the pattern of clues (see
+	// sudoku.Symmetries).
+	Symmetries []string `json:"symmetries,omitempty"`
+
+	// Difficulty is the difficulty report of the puzzle.
+	Difficulty sudoku.DifficultyReport `json:"difficulty"`
+
+	// Techniques is the technique profile of the puzzle.
+	Techniques TechniqueProfile `json:"techniques"`
+
+	// Seen is set when the puzzle was marked as seen with MarkSeen.
+	Seen bool `json:"-"`
+}
+
+// TechniqueProfile summarizes the technique-based rating of a puzzle (see
+// sudoku.EvaluateTechniqueDifficulty).
+type TechniqueProfile struct {
+	Rating  float64 `json:"rating"`
+	Tier    string  `json:"tier"`
+	Hardest string  `json:"hardest,omitempty"`
+	Solved  bool    `json:"solved"`
+
+	// Counts counts how many times each technique was used, by name.
+	Counts map[string]int `json:"counts"`
+}
+
+// Options configures a Store.
+type Options struct {
+	// Difficulty configures the difficulty reports of added puzzles. Stored
+	// reports should be the same whenever a puzzle is added, so a Mode of
+	// sudoku.SearchRandom (the zero value) is replaced by sudoku.SearchSeeded.
+	Difficulty sudoku.DifficultyOptions
+}
+
+// Store is a library of puzzles backed by a file. It's safe for concurrent
+// use.
+type Store struct {
+	mu   sync.Mutex
+	f    *os.File
+	opts Options
+
+	entries map[string]*Entry
+
+	// byDifficulty holds the entries sorted by difficulty score, and by key
+	// for equal scores.
+	byDifficulty []*Entry
+
+	// truncated is the size of the partial record Open removed.
+	truncated int64
+}
+
+// record is a line in the store's file: exactly one of its fields is set.
+type record struct {
+	Add  *Entry `json:"add,omitempty"`
+	Seen string `json:"seen,omitempty"`
+}
+
+// Open opens the store in the given file, creating it if it doesn't exist. A
+// partial record at the end of the file, left by a crash while writing it, is
+// removed (see Truncated); other invalid records are errors.
+func Open(path string, opts Options) (*Store, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Difficulty.Mode == sudoku.SearchRandom {
+		opts.Difficulty.Mode = sudoku.SearchSeeded
+	}
+	s := &Store{f: f, opts: opts, entries: make(map[string]*Entry)}
+	if err := s.load(path); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads the records of the store's file into the in-memory indices.
+func (s *Store) load(path string) error {
+	r := bufio.NewReader(s.f)
+	var offset int64
+	lineno := 0
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			return nil
+		} else if err != nil && err != io.EOF {
+			return err
+		}
+		lineno++
+
+		var rec record
+		if jsonErr := json.Unmarshal(line, &rec); jsonErr != nil {
+			if err == io.EOF {
+				// Records are written with their newline at once, so a last
+				// line without one is a partial record.
+				s.truncated = int64(len(line))
+				return s.f.Truncate(offset)
+			}
+			return fmt.Errorf("%s:%d: %w", path, lineno, jsonErr)
+		}
+		if err == io.EOF {
+			// The record is complete, but records appended after it must
+			// start on a new line.
+			if _, err := s.f.Write([]byte{'\n'}); err != nil {
+				return err
+			}
+		}
+		offset += int64(len(line))
+
+		switch {
+		case rec.Add != nil:
+			if _, ok := s.entries[rec.Add.Key]; !ok {
+				s.insert(rec.Add)
+			}
+		case len(rec.Seen) > 0:
+			if e, ok := s.entries[rec.Seen]; ok {
+				e.Seen = true
+			}
+		default:
+			return fmt.Errorf("%s:%d: empty record", path, lineno)
+		}
+	}
+}
+
+// Truncated returns the size in bytes of the partial record that Open removed
+// from the end of the store's file, or 0 if there was none.
+func (s *Store) Truncated() int64 {
+	return s.truncated
+}
+
+// Close closes the store's file.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
+
+// Len returns the number of puzzles in the store.
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// insert adds e to the in-memory indices.
+func (s *Store) insert(e *Entry) {
+	s.entries[e.Key] = e
+	i, _ := slices.BinarySearchFunc(s.byDifficulty, e, compareEntries)
+	s.byDifficulty = slices.Insert(s.byDifficulty, i, e)
+}
+
+func compareEntries(a, b *Entry) int {
+	switch {
+	case a.Difficulty.Score < b.Difficulty.Score:
+		return -1
+	case a.Difficulty.Score > b.Difficulty.Score:
+		return 1
+	}
+	return strings.Compare(a.Key, b.Key)
+}
+
+// append writes rec to the store's file.
+func (s *Store) append(rec record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = s.f.Write(append(data, '\n'))
+	return err
+}
+
+// Add rates a puzzle and adds it to the store, unless an equivalent puzzle is
+// already there. It returns the entry of the puzzle in the store, and whether
+// it was added. It returns an error if the puzzle isn't a board of 81 squares
+// or doesn't have a single solution.
+func (s *Store) Add(values sudoku.Values) (Entry, bool, error) {
+	if len(values) != 81 {
+		return Entry{}, false, fmt.Errorf("got board of %v squares, want 81", len(values))
+	}
+	canon, _ := sudoku.Canonicalize(values)
+	key := boardLine(canon)
+
+	s.mu.Lock()
+	e, ok := s.entries[key]
+	s.mu.Unlock()
+	if ok {
+		return *e, false, nil
+	}
+
+	// Rating takes a while, so it's done without holding the lock.
+	techniques, err := sudoku.EvaluateTechniqueDifficulty(values)
+	if err != nil {
+		return Entry{}, false, err
+	}
+	report, err := sudoku.EvaluateDifficultyReport(values, s.opts.Difficulty)
+	if err != nil {
+		return Entry{}, false, err
+	}
+	e = &Entry{
+		Key:        key,
+		Board:      boardLine(values),
+		Clues:      sudoku.CountHints(values),
+		Difficulty: report,
+		Techniques: TechniqueProfile{
+			Rating: techniques.Rating,
+			Tier:   techniques.Tier.String(),
+			Solved: techniques.Solved,
+			Counts: make(map[string]int),
+		},
+	}
+	for _, sym := range sudoku.Symmetries(values) {
+		e.Symmetries = append(e.Symmetries, sym.String())
+	}
+	if techniques.Solved {
+		e.Techniques.Hardest = techniques.Hardest.String()
+	}
+	for t, n := range techniques.Histogram {
+		e.Techniques.Counts[t.String()] = n
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[key]; ok {
+		return *e, false, nil
+	}
+	if err := s.append(record{Add: e}); err != nil {
+		return Entry{}, false, err
+	}
+	s.insert(e)
+	return *e, true, nil
+}
+
+// Lookup returns the entry of the puzzle equivalent to values, if it's in the
+// store.
+func (s *Store) Lookup(values sudoku.Values) (Entry, bool) {
+	if len(values) != 81 {
+		return Entry{}, false
+	}
+	canon, _ := sudoku.Canonicalize(values)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[boardLine(canon)]; ok {
+		return *e, true
+	}
+	return Entry{}, false
+}
+
+// MarkSeen marks the puzzles with the given keys as seen, e.g. after they're
+// served to a user. Keys not in the store are ignored.
+func (s *Store) MarkSeen(keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		e, ok := s.entries[key]
+		if !ok || e.Seen {
+			continue
+		}
+		if err := s.append(record{Seen: key}); err != nil {
+			return err
+		}
+		e.Seen = true
+	}
+	return nil
+}
+
+// Query selects puzzles from the store. Zero values of fields don't restrict
+// the puzzles selected.
+type Query struct {
+	// MinDifficulty and MaxDifficulty bound the difficulty score.
+	MinDifficulty, MaxDifficulty float64
+
+	// MinRating and MaxRating bound the technique rating.
+	MinRating, MaxRating float64
+
+	// Techniques lists techniques that the puzzles must use.
+	Techniques []sudoku.Technique
+
+	// Symmetry is a symmetry that the pattern of clues must have.
+	Symmetry sudoku.Symmetry
+
+	// Unseen selects only puzzles that weren't marked as seen.
+	Unseen bool
+
+	// Limit is the maximal number of puzzles to return.
+	Limit int
+}
+
+// Query returns the puzzles selected by q, sorted by difficulty score. For
+// example, 10 unseen puzzles with a score between 3.0 and 3.5 are selected
+// with:
+//
+//	s.Query(Query{MinDifficulty: 3.0, MaxDifficulty: 3.5, Unseen: true, Limit: 10})
+func (s *Store) Query(q Query) []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	start, _ := slices.BinarySearchFunc(s.byDifficulty, q.MinDifficulty, func(e *Entry, min float64) int {
+		if e.Difficulty.Score < min {
+			return -1
+		}
+		return 1
+	})
+
+	var entries []Entry
+	for _, e := range s.byDifficulty[start:] {
+		if q.MaxDifficulty > 0 && e.Difficulty.Score > q.MaxDifficulty {
+			break
+		}
+		if q.Limit > 0 && len(entries) >= q.Limit {
+			break
+		}
+		if q.matches(e) {
+			entries = append(entries, *e)
+		}
+	}
+	return entries
+}
+
+// matches reports whether e matches the criteria of q other than the
+// difficulty score and the limit.
+func (q Query) matches(e *Entry) bool {
+	if q.Unseen && e.Seen {
+		return false
+	}
+	if e.Techniques.Rating < q.MinRating || (q.MaxRating > 0 && e.Techniques.Rating > q.MaxRating) {
+		return false
+	}
+	for _, t := range q.Techniques {
+		if e.Techniques.Counts[t.String()] == 0 {
+			return false
+		}
+	}
+	if q.Symmetry != sudoku.SymmetryNone && !slices.Contains(e.Symmetries, q.Symmetry.String()) {
+		return false
+	}
+	return true
+}
+
+// Import adds the puzzles in r to the store. r has boards in any of the
+// formats read by sudoku.BoardReader, like the one-line text format written by
+// Export and by the generator command. It returns the number of puzzles added,
+// and the number of puzzles that were already in the store.
+func (s *Store) Import(r io.Reader) (added, duplicates int, err error) {
+	br := sudoku.NewBoardReader(r)
+	for {
+		p, err := br.Read()
+		if err == io.EOF {
+			return added, duplicates, nil
+		} else if err != nil {
+			return added, duplicates, err
+		}
+		_, ok, err := s.Add(p.Board)
+		if err != nil {
+			return added, duplicates, fmt.Errorf("line %v: %w", br.Line(), err)
+		}
+		if ok {
+			added++
+		} else {
+			duplicates++
+		}
+	}
+}
+
+// Export writes entries to w in the one-line text format of
+// sudoku.FormatText, numbering them from 1:
+//
+//	<board> #<number> Difficulty: <score>
+func Export(w io.Writer, entries []Entry) error {
+	bw := bufio.NewWriter(w)
+	for i, e := range entries {
+		fmt.Fprintf(bw, "%s #%d Difficulty: %.2f\n", e.Board, i+1, e.Difficulty.Score)
+	}
+	return bw.Flush()
+}
+
+// boardLine returns the board on a single line, with '.' for empty squares.
+func boardLine(values sudoku.Values) string {
+	var sb strings.Builder
+	for _, d := range values {
+		if d.Size() == 1 {
+			sb.WriteString(d.String())
+		} else {
+			sb.WriteByte('.')
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,219 @@
+package store
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eliben/go-sudoku"
+)
+
+var testBoards = []string{
+	"003020600900305001001806400008102900700000008006708200002609500800203009005010300",
+	"4.....8.5.3..........7......2.....6.....8.4......1.......6.3.7.5..2.....1.4......",
+	"..3.2.6..9..3.5..1..18.64....81.29..7.......8..67.82....26.95..8..2.3..9..5.1.3..",
+}
+
+func openTestStore(t *testing.T, path string) *Store {
+	s, err := Open(path, Options{Difficulty: sudoku.DifficultyOptions{Mode: sudoku.SearchSeeded, Seed: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "puzzles.jsonl")
+	s := openTestStore(t, path)
+
+	// The third board is the first one with blanks written differently, and
+	// a transformation of it is equivalent too.
+	v, err := sudoku.ParseBoard(testBoards[0], false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transformed := sudoku.RandomTransform(rand.New(rand.NewSource(1))).Apply(v)
+	var sb strings.Builder
+	for _, b := range testBoards {
+		sb.WriteString(b + " #1 Difficulty: 1.00\n")
+	}
+	sb.WriteString("# comment\n\n")
+
+	added, dups, err := s.Import(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 2 || dups != 1 {
+		t.Errorf("got added=%v, duplicates=%v, want 2, 1", added, dups)
+	}
+	if _, ok, err := s.Add(transformed); ok || err != nil {
+		t.Errorf("got added=%v, err=%v for equivalent puzzle", ok, err)
+	}
+	e, ok := s.Lookup(transformed)
+	if !ok || e.Board != strings.ReplaceAll(testBoards[0], "0", ".") || e.Clues != 32 {
+		t.Errorf("got entry %+v for equivalent puzzle", e)
+	}
+
+	if err := s.MarkSeen(e.Key, "not a key"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Everything is there after reopening.
+	s = openTestStore(t, path)
+	defer s.Close()
+	if s.Len() != 2 {
+		t.Errorf("got %v puzzles after reopening, want 2", s.Len())
+	}
+	all := s.Query(Query{})
+	if len(all) != 2 || all[0].Difficulty.Score > all[1].Difficulty.Score {
+		t.Fatalf("got entries not sorted by difficulty: %+v", all)
+	}
+	if unseen := s.Query(Query{Unseen: true}); len(unseen) != 1 || unseen[0].Key == e.Key {
+		t.Errorf("got unseen %+v", unseen)
+	}
+
+	// Round trip through the text format.
+	var buf bytes.Buffer
+	if err := Export(&buf, all); err != nil {
+		t.Fatal(err)
+	}
+	s2 := openTestStore(t, filepath.Join(t.TempDir(), "copy.jsonl"))
+	defer s2.Close()
+	if added, _, err := s2.Import(&buf); added != 2 || err != nil {
+		t.Errorf("got added=%v, err=%v importing export", added, err)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	s := openTestStore(t, filepath.Join(t.TempDir(), "puzzles.jsonl"))
+	defer s.Close()
+	for _, b := range testBoards[:2] {
+		v, err := sudoku.ParseBoard(b, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := s.Add(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	all := s.Query(Query{})
+	easy, hard := all[0], all[1]
+
+	var tests = []struct {
+		q    Query
+		want []Entry
+	}{
+		{Query{Limit: 1}, []Entry{easy}},
+		{Query{MinDifficulty: hard.Difficulty.Score}, []Entry{hard}},
+		{Query{MaxDifficulty: easy.Difficulty.Score}, []Entry{easy}},
+		{Query{MinRating: hard.Techniques.Rating}, []Entry{hard}},
+		{Query{MaxRating: easy.Techniques.Rating}, []Entry{easy}},
+		{Query{Techniques: []sudoku.Technique{sudoku.LockedCandidates}}, []Entry{hard}},
+		{Query{Symmetry: sudoku.SymmetryRotational180}, []Entry{easy}},
+	}
+	for _, tt := range tests {
+		got := s.Query(tt.q)
+		if len(got) != len(tt.want) || (len(got) > 0 && got[0].Key != tt.want[0].Key) {
+			t.Errorf("Query(%+v) got %v entries, want %v", tt.q, len(got), len(tt.want))
+		}
+	}
+}
+
+func TestAddErrors(t *testing.T) {
+	s := openTestStore(t, filepath.Join(t.TempDir(), "puzzles.jsonl"))
+	defer s.Close()
+	v, err := sudoku.ParseBoard("123456789"+strings.Repeat(".", 72), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Add(v); err == nil {
+		t.Errorf("got no error for puzzle with multiple solutions")
+	}
+	if _, _, err := s.Import(strings.NewReader("12345\n")); err == nil {
+		t.Errorf("got no error for invalid board")
+	}
+}
+
+func TestAddInvalidBoard(t *testing.T) {
+	s := openTestStore(t, filepath.Join(t.TempDir(), "puzzles.jsonl"))
+	defer s.Close()
+	short := sudoku.EmptyBoard()[:80]
+	if _, _, err := s.Add(short); err == nil {
+		t.Errorf("got no error for board of 80 squares")
+	}
+	if _, ok := s.Lookup(short); ok {
+		t.Errorf("got entry for board of 80 squares")
+	}
+}
+
+func TestOpenPartialRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "puzzles.jsonl")
+	s := openTestStore(t, path)
+	if _, _, err := s.Import(strings.NewReader(testBoards[0])); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// A crash while adding a puzzle leaves part of its record.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	partial := `{"add":{"key":"..3.2`
+	f.WriteString(partial)
+	f.Close()
+
+	s = openTestStore(t, path)
+	if s.Len() != 1 || s.Truncated() != int64(len(partial)) {
+		t.Errorf("got %v puzzles and %v bytes truncated, want 1 and %v", s.Len(), s.Truncated(), len(partial))
+	}
+	if _, _, err := s.Import(strings.NewReader(testBoards[1])); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s = openTestStore(t, path)
+	defer s.Close()
+	if s.Len() != 2 || s.Truncated() != 0 {
+		t.Errorf("got %v puzzles and %v bytes truncated, want 2 and 0", s.Len(), s.Truncated())
+	}
+}
+
+func TestOpenInvalidRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "puzzles.jsonl")
+	if err := os.WriteFile(path, []byte("{\"seen\":\"x\"}\nnot json\n{\"seen\":\"y\"}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path, Options{}); err == nil {
+		t.Errorf("got no error for invalid record")
+	}
+}
+
+func TestDefaultDifficultyReproducible(t *testing.T) {
+	v, err := sudoku.ParseBoard(testBoards[1], false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reports []sudoku.DifficultyReport
+	for i := 0; i < 2; i++ {
+		s, err := Open(filepath.Join(t.TempDir(), "puzzles.jsonl"), Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		e, _, err := s.Add(v)
+		s.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		reports = append(reports, e.Difficulty)
+	}
+	if reports[0] != reports[1] {
+		t.Errorf("got different reports for the same puzzle:\n%v\n%v", reports[0], reports[1])
+	}
+}
@@ -1,6 +1,7 @@
 package sudoku
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"math/rand"
@@ -52,6 +53,9 @@ var units [][]Unit
 // some unit with this index (it won't contain the index itself).
 var peers [][]Index
 
+// peerSets[i] holds the same squares as peers[i], as a squareSet.
+var peerSets [81]squareSet
+
 func init() {
 	index := func(row, col int) Index {
 		return row*9 + col
@@ -110,6 +114,7 @@ func init() {
 				// its speed.
 				if candidate != i && slices.Index(peers[i], candidate) < 0 {
 					peers[i] = append(peers[i], candidate)
+					peerSets[i].add(candidate)
 				}
 			}
 		}
@@ -164,6 +169,50 @@ func ParseBoard(str string, runElimination bool) (Values, error) {
 	return values, nil
 }
 
+// ParseCandidates parses a pencil-mark grid from str, as produced by Display:
+// the candidates of each square as a group of digits, in row-major order.
+// Groups are separated by any runes other than the digits 1-9, so grids in
+// HoDoKu's PM format, with their borders, are parsed too. A square with a
+// single candidate is solved.
+//
+// It returns an error if there aren't 81 groups, or if the grid isn't
+// consistent: if two solved squares in a unit have the same digit, or if a
+// digit isn't a candidate anywhere in a unit. Candidates that conflict with
+// solved squares are kept, so that grids of boards without elimination
+// round-trip with Display.
+func ParseCandidates(str string) (Values, error) {
+	groups := strings.FieldsFunc(str, func(r rune) bool {
+		return r < '1' || r > '9'
+	})
+	if len(groups) != 81 {
+		return nil, fmt.Errorf("got %v squares in candidate grid, want 81", len(groups))
+	}
+
+	values := make(Values, 81)
+	for sq, group := range groups {
+		for _, r := range group {
+			values[sq] = values[sq].Add(uint16(r - '0'))
+		}
+	}
+
+	for _, unit := range unitlist {
+		var all, solved Digits
+		for _, sq := range unit {
+			all |= values[sq]
+			if values[sq].Size() == 1 {
+				if solved&values[sq] != 0 {
+					return nil, fmt.Errorf("digit %v solved twice in unit with square %v", values[sq], sq)
+				}
+				solved |= values[sq]
+			}
+		}
+		if missing := FullDigitsSet().RemoveAll(all); missing != 0 {
+			return nil, fmt.Errorf("digits %v have no place in unit with square %v", missing, unit[0])
+		}
+	}
+	return values, nil
+}
+
 // EliminateAll runs elimination on all assigned squares in values. It applies
 // first-order Sudoku heuristics on the entire board. Returns true if the
 // elimination is successful, and false if the board has a contradiction.
@@ -204,15 +253,35 @@ func CountHints(values Values) int {
 // It returns true if the assignment succeeded, and false if the assignment
 // fails resulting in an invalid Sudoku board.
 func assign(values Values, square Index, digit uint16) bool {
+	return assignTraced(values, square, digit, nil, nil)
+}
+
+// eliminate removes digit from the candidates in values[square], propagating
+// constraints. values is modified.
+// It returns false if this results in an invalid Sudoku board; otherwise
+// returns true.
+func eliminate(values Values, square Index, digit uint16) bool {
+	return eliminateTraced(values, square, digit, nil, nil)
+}
+
+// assignTraced is assign with an optional tracer that records the implications
+// made while propagating; cause is the implication that led to this
+// assignment. When tr is nil, no tracing is done.
+func assignTraced(values Values, square Index, digit uint16, tr *tracer, cause *Implication) bool {
 	if EnableStats {
 		Stats.NumAssigns++
 	}
+	if tr.depthReached(cause) {
+		// The assignment is the deepest implication the tracer wants, so don't
+		// propagate it.
+		return true
+	}
 
 	for d := uint16(1); d <= 9; d++ {
 		// For each d 1..9 that's != digit, if d is set in
 		// values[square], try to eliminate it.
 		if values[square].IsMember(d) && d != digit {
-			if !eliminate(values, square, d) {
+			if !eliminateTraced(values, square, d, tr, cause) {
 				return false
 			}
 		}
@@ -220,11 +289,8 @@ func assign(values Values, square Index, digit uint16) bool {
 	return true
 }
 
-// eliminate removes digit from the candidates in values[square], propagating
-// constraints. values is modified.
-// It returns false if this results in an invalid Sudoku board; otherwise
-// returns true.
-func eliminate(values Values, square Index, digit uint16) bool {
+// eliminateTraced is eliminate with an optional tracer; see assignTraced.
+func eliminateTraced(values Values, square Index, digit uint16, tr *tracer, cause *Implication) bool {
 	if !values[square].IsMember(digit) {
 		// Already eliminated
 		return true
@@ -232,18 +298,31 @@ func eliminate(values Values, square Index, digit uint16) bool {
 
 	// Remove digit from the candidates in square.
 	values[square] = values[square].Remove(digit)
+	node := tr.eliminated(square, digit, cause)
 
-	switch values[square].Size() {
-	case 0:
+	if values[square].Size() == 0 {
 		// No remaining options for square -- this is a contradiction.
+		if tr != nil {
+			tr.contradiction(node, square, "no candidates left in "+squareName(square))
+		}
 		return false
-	case 1:
+	}
+	if tr.depthReached(node) {
+		// The tracer doesn't want implications deeper than this one, so stop
+		// propagating.
+		return true
+	}
+
+	if values[square].Size() == 1 {
 		// A single digit candidate remaining in the square -- this creates a new
 		// constraint. Eliminate this digit from all peer squares.
 		remaining := values[square].SingleMemberDigit()
-		for _, peer := range peers[square] {
-			if !eliminate(values, peer, remaining) {
-				return false
+		single := tr.placed(square, remaining, node, nil)
+		if !tr.depthReached(single) {
+			for _, peer := range peers[square] {
+				if !eliminateTraced(values, peer, remaining, tr, single) {
+					return false
+				}
 			}
 		}
 	}
@@ -268,12 +347,16 @@ UnitLoop:
 		}
 		if sqd == -1 {
 			// Contradiction: no places left in this unit for 'digit'
+			if tr != nil {
+				tr.contradiction(node, -1, fmt.Sprintf("no place left for %d in %s", digit, unitNameOf(unit)))
+			}
 			return false
 		}
 
 		// There's only a single place left in the unit for 'digit' to go, so
 		// assign it.
-		if !assign(values, sqd, digit) {
+		hidden := tr.placed(sqd, digit, node, unit)
+		if !assignTraced(values, sqd, digit, tr, hidden) {
 			return false
 		}
 	}
@@ -343,6 +426,62 @@ func DisplayAsInput(values Values) string {
 	return sb.String()
 }
 
+// MarshalText implements the encoding.TextMarshaler interface for Values, so
+// that boards are encoded as strings, e.g. in JSON. If every square is either
+// solved or has all the candidates, the board is encoded compactly as 81
+// runes: the digit of each solved square, and . for the others (as read by
+// ParseBoard). Otherwise, it's encoded as the candidates of each square
+// separated by single spaces (as read by ParseCandidates). It returns an error
+// if the board doesn't have 81 squares, or if a square has no candidates.
+func (values Values) MarshalText() ([]byte, error) {
+	if len(values) != 81 {
+		return nil, fmt.Errorf("got board with %v squares, want 81", len(values))
+	}
+
+	compact := true
+	for sq, d := range values {
+		if d == 0 {
+			return nil, fmt.Errorf("square %v has no candidates", sq)
+		}
+		if d.Size() != 1 && d != FullDigitsSet() {
+			compact = false
+		}
+	}
+
+	var sb strings.Builder
+	for sq, d := range values {
+		switch {
+		case compact && d.Size() == 1:
+			sb.WriteString(d.String())
+		case compact:
+			sb.WriteByte('.')
+		default:
+			if sq > 0 {
+				sb.WriteByte(' ')
+			}
+			sb.WriteString(d.String())
+		}
+	}
+	return []byte(sb.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface for Values,
+// decoding the formats written by MarshalText.
+func (values *Values) UnmarshalText(text []byte) error {
+	var v Values
+	var err error
+	if bytes.ContainsRune(text, ' ') {
+		v, err = ParseCandidates(string(text))
+	} else {
+		v, err = ParseBoard(string(text), false)
+	}
+	if err != nil {
+		return err
+	}
+	*values = v
+	return nil
+}
+
 // DisplayAsSVG write the board's visual representation in SVG format into w.
 // The difficulty is emitted too.
 func DisplayAsSVG(w io.Writer, values Values, difficulty float64) {
@@ -379,6 +518,77 @@ func DisplayAsSVG(w io.Writer, values Values, difficulty float64) {
 	canvas.End()
 }
 
+// stepHighlightColors are the fill colors used by DisplayStepAsSVG to
+// highlight the pattern of a step; ALSs use the colors after the first, in
+// order.
+var stepHighlightColors = []string{"#fff3a0", "#b8e0ff", "#c8f0c0", "#f5c8f0", "#ffd8a8", "#d8d0ff"}
+
+// DisplayStepAsSVG writes a visual explanation of a logical solver step into w,
+// in SVG format. values is the board the step applies to; all candidates of
+// unsolved squares are shown. The squares of the step's pattern are
+// highlighted (each ALS in its own color), eliminated candidates are shown in
+// red and placed digits in green. The step's description is emitted too.
+func DisplayStepAsSVG(w io.Writer, values Values, step Step) {
+	startX := 50
+	startY := 50
+	width := 800
+	height := 900
+	cellsize := 80
+	canvas := svg.New(w, width, height)
+
+	fills := make(map[Index]string)
+	for _, sq := range step.Cells {
+		fills[sq] = stepHighlightColors[0]
+	}
+	for i, als := range step.ALS {
+		for _, sq := range als.Cells {
+			fills[sq] = stepHighlightColors[1+i%(len(stepHighlightColors)-1)]
+		}
+	}
+
+	for sq, d := range values {
+		col := sq % 9
+		x := startX + col*cellsize
+
+		row := sq / 9
+		y := startY + row*cellsize
+
+		fill, ok := fills[sq]
+		if !ok {
+			fill = "white"
+		}
+		canvas.Rect(x, y, cellsize, cellsize, "stroke:black; stroke-width:2; fill:"+fill)
+		if d.Size() == 1 {
+			canvas.Text(x+cellsize/2, y+cellsize/2, d.String(), "text-anchor:middle; dominant-baseline:middle; font-family:Helvetica; font-size:32px; fill:black")
+			continue
+		}
+
+		// Draw candidates in a 3x3 grid inside the square.
+		for _, digit := range digitsOf(d) {
+			color := "dimgray"
+			if slices.Contains(step.Eliminations, Candidate{sq, digit}) {
+				color = "red"
+			} else if slices.Contains(step.Placements, Candidate{sq, digit}) {
+				color = "green"
+			}
+			cx := x + cellsize/6 + int(digit-1)%3*cellsize/3
+			cy := y + cellsize/6 + int(digit-1)/3*cellsize/3
+			canvas.Text(cx, cy, fmt.Sprint(digit), "text-anchor:middle; dominant-baseline:middle; font-family:Helvetica; font-size:16px; fill:"+color)
+		}
+	}
+
+	// Wider squares around 3x3 blocks
+	for br := 0; br < 3; br++ {
+		for bc := 0; bc < 3; bc++ {
+			canvas.Rect(startX+bc*cellsize*3, startY+br*cellsize*3, cellsize*3, cellsize*3, "stroke:black; stroke-width:5; fill-opacity:0.0")
+		}
+	}
+
+	canvas.Text(startX, startY+9*cellsize+cellsize/2, step.Description, "font-family:Helvetica; font-size:16px; fill:black")
+
+	canvas.End()
+}
+
 // EmptyBoard creates an "empty" Sudoku board, where each square can potentially
 // contain any digit.
 func EmptyBoard() Values {
@@ -428,8 +638,16 @@ type SolveOptions struct {
 	// Randomize tells the solver to randomly shuffle its digit selection when
 	// attempting to guess a value for a square. For actual randomness, the
 	// rand package's default randomness source should be properly seeded before
-	// invoking Solve.
+	// invoking Solve, or Rand should be set.
 	Randomize bool
+
+	// Rand is the source of randomness used when Randomize is set. If nil,
+	// the rand package's default source is used.
+	Rand *rand.Rand
+
+	// searches, if not nil, is incremented for every search (guess) the solver
+	// makes, instead of Stats. Unlike Stats, it's safe to use concurrently.
+	searches *uint64
 }
 
 // Solve runs a backtracking search to solve the board given in values.
@@ -450,13 +668,19 @@ func Solve(values Values, options ...SolveOptions) (Values, bool) {
 		return values, true
 	}
 
-	if EnableStats {
+	if len(options) > 0 && options[0].searches != nil {
+		*options[0].searches++
+	} else if EnableStats {
 		Stats.NumSearches++
 	}
 
 	var candidates = []uint16{1, 2, 3, 4, 5, 6, 7, 8, 9}
 	if len(options) > 0 && options[0].Randomize {
-		rand.Shuffle(len(candidates), func(i, j int) {
+		shuffle := rand.Shuffle
+		if options[0].Rand != nil {
+			shuffle = options[0].Rand.Shuffle
+		}
+		shuffle(len(candidates), func(i, j int) {
 			candidates[i], candidates[j] = candidates[j], candidates[i]
 		})
 	}
@@ -138,6 +138,61 @@ func TestParseBoard(t *testing.T) {
 	}
 }
 
+func TestParseCandidates(t *testing.T) {
+	// Boards round-trip with Display, with and without elimination.
+	for _, eliminate := range []bool{false, true} {
+		v, err := ParseBoard(hardboard1, eliminate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ParseCandidates(Display(v))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, v) {
+			t.Errorf("got different board after round trip:\n%v", Display(got))
+		}
+	}
+
+	// HoDoKu's PM format.
+	pm := `.----------------------.----------------------.----------------------.
+| 4      1679   12679  | 139    2369   269    | 8      1239   5      |
+| 26789  3      1256789| 14589  24569  245689 | 12679  1249   124679 |
+| 2689   15689  125689 | 7      234569 245689 | 12369  12349  123469 |
+:----------------------+----------------------+----------------------:
+| 3789   2      15789  | 3459   34579  4579   | 13579  6      13789  |
+| 3679   15679  15679  | 359    8      25679  | 4      12359  12379  |
+| 36789  4      56789  | 359    1      25679  | 23579  23589  23789  |
+:----------------------+----------------------+----------------------:
+| 289    89     289    | 6      459    3      | 1259   7      12489  |
+| 5      6789   3      | 2      479    1      | 69     489    4689   |
+| 1      6789   4      | 589    579    5789   | 23569  23589  23689  |
+'----------------------'----------------------'----------------------'`
+	got, err := ParseCandidates(pm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ParseBoard(hardboard1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got board from PM grid:\n%v", Display(got))
+	}
+
+	// Errors: too few squares, a digit solved twice in a row, and a digit
+	// without a place in a row.
+	for _, bad := range []string{
+		strings.Repeat("123456789 ", 80),
+		"1 1 " + strings.Repeat("123456789 ", 79),
+		strings.Repeat("12345678 ", 9) + strings.Repeat("123456789 ", 72),
+	} {
+		if _, err := ParseCandidates(bad); err == nil {
+			t.Errorf("got no error for grid %q", bad)
+		}
+	}
+}
+
 func TestSolveBoard(t *testing.T) {
 	v, err := ParseBoard(hardboard1, true)
 	if err != nil {
@@ -0,0 +1,152 @@
+package sudoku
+
+import (
+	"fmt"
+
+	"golang.org/x/exp/slices"
+)
+
+// Symmetry is a symmetry of the pattern of hints on a board: for each square
+// that has a hint, the squares it's mapped to by the symmetry have hints too.
+type Symmetry int
+
+const (
+	// SymmetryNone means no symmetry.
+	SymmetryNone Symmetry = iota
+
+	// SymmetryRotational180 is symmetry under rotation by 180 degrees.
+	SymmetryRotational180
+
+	// SymmetryRotational90 is symmetry under rotation by 90 degrees (and
+	// therefore also 180 and 270 degrees).
+	SymmetryRotational90
+
+	// SymmetryHorizontal is mirror symmetry across the horizontal axis (the
+	// middle row).
+	SymmetryHorizontal
+
+	// SymmetryVertical is mirror symmetry across the vertical axis (the middle
+	// column).
+	SymmetryVertical
+
+	// SymmetryDiagonal is mirror symmetry across the main diagonal, from the
+	// top-left to the bottom-right corner.
+	SymmetryDiagonal
+
+	// SymmetryAntiDiagonal is mirror symmetry across the anti-diagonal, from
+	// the top-right to the bottom-left corner.
+	SymmetryAntiDiagonal
+
+	// SymmetryDihedral is full dihedral symmetry: under all rotations and
+	// mirrors of the square.
+	SymmetryDihedral
+)
+
+var symmetryNames = []string{"none", "rot180", "rot90", "horizontal", "vertical", "diagonal", "antidiagonal", "dihedral"}
+
+// String implements the fmt.Stringer interface for Symmetry.
+func (s Symmetry) String() string {
+	if s >= 0 && int(s) < len(symmetryNames) {
+		return symmetryNames[s]
+	}
+	return fmt.Sprintf("Symmetry(%d)", int(s))
+}
+
+// ParseSymmetry returns the symmetry with the given name, as returned by
+// Symmetry.String.
+func ParseSymmetry(name string) (Symmetry, error) {
+	if i := slices.Index(symmetryNames, name); i >= 0 {
+		return Symmetry(i), nil
+	}
+	return SymmetryNone, fmt.Errorf("unknown symmetry %q", name)
+}
+
+// squareMap maps each square to another square.
+type squareMap func(row, col int) (int, int)
+
+// generators returns the square maps that generate the symmetry group of s.
+func (s Symmetry) generators() []squareMap {
+	rot180 := func(r, c int) (int, int) { return 8 - r, 8 - c }
+	rot90 := func(r, c int) (int, int) { return c, 8 - r }
+	horizontal := func(r, c int) (int, int) { return 8 - r, c }
+	vertical := func(r, c int) (int, int) { return r, 8 - c }
+	diagonal := func(r, c int) (int, int) { return c, r }
+	antiDiagonal := func(r, c int) (int, int) { return 8 - c, 8 - r }
+
+	switch s {
+	case SymmetryNone:
+		return nil
+	case SymmetryRotational180:
+		return []squareMap{rot180}
+	case SymmetryRotational90:
+		return []squareMap{rot90}
+	case SymmetryHorizontal:
+		return []squareMap{horizontal}
+	case SymmetryVertical:
+		return []squareMap{vertical}
+	case SymmetryDiagonal:
+		return []squareMap{diagonal}
+	case SymmetryAntiDiagonal:
+		return []squareMap{antiDiagonal}
+	case SymmetryDihedral:
+		return []squareMap{rot90, horizontal}
+	}
+	panic(fmt.Sprintf("unknown symmetry %d", int(s)))
+}
+
+// Orbits returns the orbits of the squares of the board under s: groups of
+// squares that are mapped to each other by the symmetry, so that a board with
+// symmetry s has hints either in all or in none of the squares of each orbit.
+// Each orbit lists its squares in increasing order, and the orbits are
+// ordered by their first square.
+func (s Symmetry) Orbits() [][]Index {
+	gens := s.generators()
+	var orbits [][]Index
+	var seen [81]bool
+	for sq := 0; sq < 81; sq++ {
+		if seen[sq] {
+			continue
+		}
+
+		// Find the closure of sq under the generators.
+		orbit := []Index{sq}
+		seen[sq] = true
+		for i := 0; i < len(orbit); i++ {
+			for _, g := range gens {
+				r, c := g(orbit[i]/9, orbit[i]%9)
+				if next := r*9 + c; !seen[next] {
+					seen[next] = true
+					orbit = append(orbit, next)
+				}
+			}
+		}
+		slices.Sort(orbit)
+		orbits = append(orbits, orbit)
+	}
+	return orbits
+}
+
+// HasSymmetry reports whether the pattern of hints on the board has symmetry
+// s.
+func HasSymmetry(values Values, s Symmetry) bool {
+	for _, orbit := range s.Orbits() {
+		for _, sq := range orbit[1:] {
+			if (values[sq].Size() == 1) != (values[orbit[0]].Size() == 1) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Symmetries returns all the symmetries the pattern of hints on the board
+// has, other than SymmetryNone, in the order they're declared.
+func Symmetries(values Values) []Symmetry {
+	var syms []Symmetry
+	for s := SymmetryRotational180; s <= SymmetryDihedral; s++ {
+		if HasSymmetry(values, s) {
+			syms = append(syms, s)
+		}
+	}
+	return syms
+}
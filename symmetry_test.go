@@ -0,0 +1,90 @@
+package sudoku
+
+import (
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestSymmetryOrbits(t *testing.T) {
+	var tests = []struct {
+		sym       Symmetry
+		numOrbits int
+		orbit     []Index // the orbit of square 1 (r1c2)
+	}{
+		{SymmetryNone, 81, []Index{1}},
+		{SymmetryRotational180, 41, []Index{1, 79}},
+		{SymmetryRotational90, 21, []Index{1, 17, 63, 79}},
+		{SymmetryHorizontal, 45, []Index{1, 73}},
+		{SymmetryVertical, 45, []Index{1, 7}},
+		{SymmetryDiagonal, 45, []Index{1, 9}},
+		{SymmetryAntiDiagonal, 45, []Index{1, 71}},
+		{SymmetryDihedral, 15, []Index{1, 7, 9, 17, 63, 71, 73, 79}},
+	}
+
+	for _, tt := range tests {
+		orbits := tt.sym.Orbits()
+		if len(orbits) != tt.numOrbits {
+			t.Errorf("%v: got %v orbits, want %v", tt.sym, len(orbits), tt.numOrbits)
+		}
+
+		var seen [81]int
+		for _, orbit := range orbits {
+			for _, sq := range orbit {
+				seen[sq]++
+			}
+			if orbit[0] == 1 && !slices.Equal(orbit, tt.orbit) {
+				t.Errorf("%v: got orbit %v, want %v", tt.sym, orbit, tt.orbit)
+			}
+		}
+		for sq, n := range seen {
+			if n != 1 {
+				t.Errorf("%v: square %v in %v orbits", tt.sym, sq, n)
+			}
+		}
+	}
+}
+
+func TestParseSymmetry(t *testing.T) {
+	for sym := SymmetryNone; sym <= SymmetryDihedral; sym++ {
+		got, err := ParseSymmetry(sym.String())
+		if err != nil || got != sym {
+			t.Errorf("ParseSymmetry(%q) = %v, %v", sym.String(), got, err)
+		}
+	}
+	if _, err := ParseSymmetry("spiral"); err == nil {
+		t.Errorf("got no error for unknown symmetry")
+	}
+}
+
+func TestHasSymmetry(t *testing.T) {
+	v, err := ParseBoard(hardboard1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !HasSymmetry(v, SymmetryNone) {
+		t.Errorf("every board has SymmetryNone")
+	}
+
+	empty := EmptyBoard()
+	empty[1] = SingleDigitSet(3)
+	empty[79] = SingleDigitSet(5)
+	if !HasSymmetry(empty, SymmetryRotational180) || HasSymmetry(empty, SymmetryRotational90) {
+		t.Errorf("got wrong symmetry for board with two hints")
+	}
+}
+
+func TestSymmetries(t *testing.T) {
+	empty := EmptyBoard()
+	empty[1] = SingleDigitSet(3)
+	empty[79] = SingleDigitSet(5)
+	if syms := Symmetries(empty); !slices.Equal(syms, []Symmetry{SymmetryRotational180}) {
+		t.Errorf("got symmetries %v, want [rot180]", syms)
+	}
+
+	// Dihedral symmetry implies all the others.
+	board := GenerateWithSymmetry(30, SymmetryDihedral)
+	if syms := Symmetries(board); len(syms) != int(SymmetryDihedral) {
+		t.Errorf("got symmetries %v for dihedral board", syms)
+	}
+}
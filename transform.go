@@ -0,0 +1,236 @@
+package sudoku
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Transform is a transformation of Sudoku boards that preserves their
+// validity: a valid board stays valid, and a puzzle keeps the same number of
+// solutions and the same difficulty. Transforms are built from permutations of
+// bands and stacks, swaps of rows within a band and of columns within a stack,
+// transposition, rotations and relabelling of digits, and combined with
+// Compose.
+//
+// The zero value of Transform is the identity.
+type Transform struct {
+	// Applying a transform first relabels each digit d to digits[d-1], then
+	// transposes the board if transpose is set, and then takes row r of the
+	// result from row rows[r] and column c from column cols[c].
+	transpose bool
+	rows      [9]int
+	cols      [9]int
+	digits    [9]uint16
+}
+
+// identityTransform returns the identity transform with explicit
+// permutations.
+func identityTransform() Transform {
+	var t Transform
+	for i := 0; i < 9; i++ {
+		t.rows[i] = i
+		t.cols[i] = i
+		t.digits[i] = uint16(i + 1)
+	}
+	return t
+}
+
+// normalized returns t with explicit permutations, replacing the zero value
+// with the identity.
+func (t Transform) normalized() Transform {
+	if t == (Transform{}) {
+		return identityTransform()
+	}
+	return t
+}
+
+// Transpose returns the transform that transposes the board, reflecting it
+// across the main diagonal.
+func Transpose() Transform {
+	t := identityTransform()
+	t.transpose = true
+	return t
+}
+
+// Rotate returns the transform that rotates the board clockwise by the given
+// number of quarter turns; negative numbers rotate counter-clockwise.
+func Rotate(quarterTurns int) Transform {
+	// A clockwise quarter turn is a transposition followed by reversing the
+	// order of the columns.
+	quarter := Transpose()
+	for c := 0; c < 9; c++ {
+		quarter.cols[c] = 8 - c
+	}
+
+	t := identityTransform()
+	for i := 0; i < ((quarterTurns%4)+4)%4; i++ {
+		t = t.Compose(quarter)
+	}
+	return t
+}
+
+// SwapBands returns the transform that swaps bands a and b (0-2), the groups
+// of three rows of boxes.
+func SwapBands(a, b int) Transform {
+	checkTransformRange("band", a, 3)
+	checkTransformRange("band", b, 3)
+	t := identityTransform()
+	for i := 0; i < 3; i++ {
+		t.rows[a*3+i], t.rows[b*3+i] = b*3+i, a*3+i
+	}
+	return t
+}
+
+// SwapStacks returns the transform that swaps stacks a and b (0-2), the
+// groups of three columns of boxes.
+func SwapStacks(a, b int) Transform {
+	checkTransformRange("stack", a, 3)
+	checkTransformRange("stack", b, 3)
+	t := identityTransform()
+	for i := 0; i < 3; i++ {
+		t.cols[a*3+i], t.cols[b*3+i] = b*3+i, a*3+i
+	}
+	return t
+}
+
+// SwapRows returns the transform that swaps rows a and b (0-8), which must be
+// in the same band.
+func SwapRows(a, b int) Transform {
+	checkTransformRange("row", a, 9)
+	checkTransformRange("row", b, 9)
+	if a/3 != b/3 {
+		panic(fmt.Sprintf("rows %v and %v are not in the same band", a, b))
+	}
+	t := identityTransform()
+	t.rows[a], t.rows[b] = b, a
+	return t
+}
+
+// SwapColumns returns the transform that swaps columns a and b (0-8), which
+// must be in the same stack.
+func SwapColumns(a, b int) Transform {
+	checkTransformRange("column", a, 9)
+	checkTransformRange("column", b, 9)
+	if a/3 != b/3 {
+		panic(fmt.Sprintf("columns %v and %v are not in the same stack", a, b))
+	}
+	t := identityTransform()
+	t.cols[a], t.cols[b] = b, a
+	return t
+}
+
+// RelabelDigits returns the transform that replaces each digit d with
+// perm[d-1]. perm must be a permutation of the digits 1-9.
+func RelabelDigits(perm [9]uint16) Transform {
+	var seen Digits
+	for _, d := range perm {
+		if d < 1 || d > 9 || seen.IsMember(d) {
+			panic(fmt.Sprintf("%v is not a permutation of the digits 1-9", perm))
+		}
+		seen = seen.Add(d)
+	}
+	t := identityTransform()
+	t.digits = perm
+	return t
+}
+
+func checkTransformRange(what string, i, n int) {
+	if i < 0 || i >= n {
+		panic(fmt.Sprintf("%s %v out of range [0, %v)", what, i, n))
+	}
+}
+
+// RandomTransform returns a transform chosen uniformly at random from all the
+// validity-preserving transforms, using rng as the source of randomness.
+func RandomTransform(rng *rand.Rand) Transform {
+	t := identityTransform()
+	t.transpose = rng.Intn(2) == 1
+
+	// permuteGroups permutes the groups of three lines in p, and the lines
+	// within each group.
+	permuteGroups := func(p *[9]int) {
+		groups := rng.Perm(3)
+		for g := 0; g < 3; g++ {
+			lines := rng.Perm(3)
+			for i := 0; i < 3; i++ {
+				p[g*3+i] = groups[g]*3 + lines[i]
+			}
+		}
+	}
+	permuteGroups(&t.rows)
+	permuteGroups(&t.cols)
+
+	for i, d := range rng.Perm(9) {
+		t.digits[i] = uint16(d + 1)
+	}
+	return t
+}
+
+// Apply returns a new board with t applied to values. Squares with several
+// candidates have each of their candidates relabelled.
+func (t Transform) Apply(values Values) Values {
+	t = t.normalized()
+	out := make(Values, len(values))
+	for r := 0; r < 9; r++ {
+		for c := 0; c < 9; c++ {
+			sr, sc := t.rows[r], t.cols[c]
+			if t.transpose {
+				sr, sc = sc, sr
+			}
+			out[r*9+c] = t.relabel(values[sr*9+sc])
+		}
+	}
+	return out
+}
+
+// relabel returns the digit set d with the digits relabelled by t.
+func (t Transform) relabel(d Digits) Digits {
+	var out Digits
+	for dn := uint16(1); dn <= 9; dn++ {
+		if d.IsMember(dn) {
+			out = out.Add(t.digits[dn-1])
+		}
+	}
+	return out
+}
+
+// Compose returns the transform that applies t and then u.
+func (t Transform) Compose(u Transform) Transform {
+	t = t.normalized()
+	u = u.normalized()
+
+	var out Transform
+	out.transpose = t.transpose != u.transpose
+	for i := 0; i < 9; i++ {
+		// When u transposes, its rows are taken from the columns of t's result
+		// and vice versa.
+		if u.transpose {
+			out.rows[i] = t.cols[u.rows[i]]
+			out.cols[i] = t.rows[u.cols[i]]
+		} else {
+			out.rows[i] = t.rows[u.rows[i]]
+			out.cols[i] = t.cols[u.cols[i]]
+		}
+		out.digits[i] = u.digits[t.digits[i]-1]
+	}
+	return out
+}
+
+// Inverse returns the transform that undoes t.
+func (t Transform) Inverse() Transform {
+	t = t.normalized()
+
+	var out Transform
+	out.transpose = t.transpose
+	for i := 0; i < 9; i++ {
+		if t.transpose {
+			out.rows[t.cols[i]] = i
+			out.cols[t.rows[i]] = i
+		} else {
+			out.rows[t.rows[i]] = i
+			out.cols[t.cols[i]] = i
+		}
+		out.digits[t.digits[i]-1] = uint16(i + 1)
+	}
+	return out
+}
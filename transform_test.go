@@ -0,0 +1,147 @@
+package sudoku
+
+import (
+	"math/rand"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestTransformPrimitives(t *testing.T) {
+	v, err := ParseBoard(hardboard1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each transform is checked against the square each value should be taken
+	// from.
+	swapBand := func(r int) int {
+		switch r / 3 {
+		case 0:
+			return r + 6
+		case 2:
+			return r - 6
+		}
+		return r
+	}
+	var tests = []struct {
+		name   string
+		tr     Transform
+		source func(r, c int) (int, int)
+	}{
+		{"identity", Transform{}, func(r, c int) (int, int) { return r, c }},
+		{"transpose", Transpose(), func(r, c int) (int, int) { return c, r }},
+		{"rotate 1", Rotate(1), func(r, c int) (int, int) { return 8 - c, r }},
+		{"rotate 2", Rotate(2), func(r, c int) (int, int) { return 8 - r, 8 - c }},
+		{"rotate -1", Rotate(-1), func(r, c int) (int, int) { return c, 8 - r }},
+		{"rotate 4", Rotate(4), func(r, c int) (int, int) { return r, c }},
+		{"swap bands", SwapBands(0, 2), func(r, c int) (int, int) { return swapBand(r), c }},
+		{"swap stacks", SwapStacks(2, 0), func(r, c int) (int, int) { return r, swapBand(c) }},
+		{"swap rows", SwapRows(3, 5), func(r, c int) (int, int) {
+			if r == 3 || r == 5 {
+				return 8 - r, c
+			}
+			return r, c
+		}},
+		{"swap columns", SwapColumns(7, 6), func(r, c int) (int, int) {
+			if c == 6 || c == 7 {
+				return r, 13 - c
+			}
+			return r, c
+		}},
+		{"transpose then swap rows", Transpose().Compose(SwapRows(0, 1)), func(r, c int) (int, int) {
+			if r < 2 {
+				r = 1 - r
+			}
+			return c, r
+		}},
+		{"swap rows then transpose", SwapRows(0, 1).Compose(Transpose()), func(r, c int) (int, int) {
+			if c < 2 {
+				c = 1 - c
+			}
+			return c, r
+		}},
+	}
+
+	for _, tt := range tests {
+		out := tt.tr.Apply(v)
+		for r := 0; r < 9; r++ {
+			for c := 0; c < 9; c++ {
+				sr, sc := tt.source(r, c)
+				if out[r*9+c] != v[sr*9+sc] {
+					t.Errorf("%s: got %v at (%v, %v), want %v from (%v, %v)", tt.name, out[r*9+c], r, c, v[sr*9+sc], sr, sc)
+				}
+			}
+		}
+	}
+}
+
+func TestRelabelDigits(t *testing.T) {
+	perm := [9]uint16{2, 3, 4, 5, 6, 7, 8, 9, 1}
+	v := Values{SingleDigitSet(1), SingleDigitSet(9), FullDigitsSet(), SingleDigitSet(3).Add(5)}
+	out := RelabelDigits(perm).Apply(append(v, EmptyBoard()[4:]...))
+	want := Values{SingleDigitSet(2), SingleDigitSet(1), FullDigitsSet(), SingleDigitSet(4).Add(6)}
+	if !slices.Equal(out[:4], want) {
+		t.Errorf("got %v, want %v", out[:4], want)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("got no panic for invalid permutation")
+		}
+	}()
+	RelabelDigits([9]uint16{1, 1, 2, 3, 4, 5, 6, 7, 8})
+}
+
+func TestSwapPanics(t *testing.T) {
+	for _, f := range []func(){
+		func() { SwapRows(2, 3) },
+		func() { SwapColumns(0, 8) },
+		func() { SwapBands(0, 3) },
+		func() { SwapStacks(-1, 0) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("got no panic")
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func TestRandomTransform(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	v, err := ParseBoard(easyboard1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	solution := SolveAll(v, 1)[0]
+
+	for i := 0; i < 20; i++ {
+		tr := RandomTransform(rng)
+		u := RandomTransform(rng)
+
+		// The transformed puzzle has the transformed solution as its only
+		// solution.
+		out := tr.Apply(v)
+		solutions := SolveAll(out, 2)
+		if len(solutions) != 1 || !slices.Equal(solutions[0], tr.Apply(solution)) {
+			t.Errorf("transformed puzzle has %v solutions, want the transformed solution", len(solutions))
+		}
+		if CountHints(out) != CountHints(v) {
+			t.Errorf("got %v hints, want %v", CountHints(out), CountHints(v))
+		}
+
+		if back := tr.Inverse().Apply(out); !slices.Equal(back, v) {
+			t.Errorf("inverse doesn't undo transform:\n%v", DisplayAsInput(back))
+		}
+		if !slices.Equal(tr.Compose(tr.Inverse()).Apply(v), v) || !slices.Equal(tr.Inverse().Compose(tr).Apply(v), v) {
+			t.Errorf("composition with inverse isn't the identity")
+		}
+		if !slices.Equal(tr.Compose(u).Apply(v), u.Apply(tr.Apply(v))) {
+			t.Errorf("composition doesn't apply transforms in order")
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package sudoku
+
+import (
+	"golang.org/x/exp/slices"
+)
+
+// This file contains uniqueness-based strategies: Unique Rectangles (types 1-6)
+// and BUG+1. They rely on the assumption that the puzzle has a single
+// solution, so the logical solver only uses them when LogicOptions.AssumeUnique
+// is set.
+//
+// A "deadly pattern" is an arrangement of candidates that, if it ever became
+// the only option, would allow two interchangeable solutions. The strategies
+// here eliminate the candidates that would lead to a deadly pattern.
+
+// rectangle is a potential unique rectangle: four unsolved squares at the
+// corners of two rows and two columns, spanning exactly two boxes, all of
+// which have both digits d1 and d2 as candidates.
+type rectangle struct {
+	// corners lists the four squares in row-major order: top-left, top-right,
+	// bottom-left, bottom-right.
+	corners [4]Index
+	d1, d2  uint16
+}
+
+// pair returns the digits the rectangle is built on as a set.
+func (r rectangle) pair() Digits {
+	return SingleDigitSet(r.d1).Add(r.d2)
+}
+
+// split partitions the corners of r into "floor" squares that contain only the
+// two digits of the rectangle, and "roof" squares that have extra candidates.
+func (r rectangle) split(values Values) (floor, roof []Index) {
+	for _, sq := range r.corners {
+		if values[sq] == r.pair() {
+			floor = append(floor, sq)
+		} else {
+			roof = append(roof, sq)
+		}
+	}
+	return floor, roof
+}
+
+// forEachRectangle calls f on each potential unique rectangle on the board,
+// stopping at and returning the first non-nil step f returns.
+func forEachRectangle(values Values, f func(r rectangle) *Step) *Step {
+	for r1 := 0; r1 < 9; r1++ {
+		for r2 := r1 + 1; r2 < 9; r2++ {
+			sameBand := r1/3 == r2/3
+			for c1 := 0; c1 < 9; c1++ {
+				for c2 := c1 + 1; c2 < 9; c2++ {
+					// Exactly two boxes: the rows share a band or the columns share a
+					// stack, but not both.
+					if sameBand == (c1/3 == c2/3) {
+						continue
+					}
+					corners := [4]Index{r1*9 + c1, r1*9 + c2, r2*9 + c1, r2*9 + c2}
+					common := FullDigitsSet()
+					for _, sq := range corners {
+						if values[sq].Size() < 2 {
+							common = 0
+							break
+						}
+						common &= values[sq]
+					}
+					if common.Size() < 2 {
+						continue
+					}
+					ds := digitsOf(common)
+					for i := 0; i < len(ds); i++ {
+						for j := i + 1; j < len(ds); j++ {
+							if step := f(rectangle{corners: corners, d1: ds[i], d2: ds[j]}); step != nil {
+								return step
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// rectangleStep creates a step for a unique rectangle technique on r.
+func rectangleStep(t Technique, r rectangle, elims []Candidate) *Step {
+	step := &Step{
+		Technique:    t,
+		Eliminations: elims,
+		Cells:        r.corners[:],
+		Digits:       r.pair(),
+	}
+	return describe(step, "deadly pattern %s in %s", r.pair(), squareNames(r.corners[:]))
+}
+
+// sharedUnits returns the units (row and/or column, and box) shared by
+// squares a and b.
+func sharedUnits(a, b Index) []int {
+	var us []int
+	for _, f := range []func(Index) int{rowOf, colOf, boxOf} {
+		if f(a) == f(b) {
+			us = append(us, f(a))
+		}
+	}
+	return us
+}
+
+// findUniqueRectangle1 finds a rectangle where three corners contain only the
+// two digits; the fourth corner can't contain either of them, otherwise the
+// rectangle would be deadly.
+func findUniqueRectangle1(values Values) *Step {
+	return forEachRectangle(values, func(r rectangle) *Step {
+		floor, roof := r.split(values)
+		if len(floor) != 3 {
+			return nil
+		}
+		elims := []Candidate{{roof[0], r.d1}, {roof[0], r.d2}}
+		return rectangleStep(UniqueRectangle1, r, elims)
+	})
+}
+
+// findUniqueRectangle2 finds a rectangle with two floor corners and two roof
+// corners in the same row or column that have exactly one (identical) extra
+// candidate. One of the roof corners must contain the extra digit, so it can
+// be eliminated from squares that see both of them.
+func findUniqueRectangle2(values Values) *Step {
+	return forEachRectangle(values, func(r rectangle) *Step {
+		floor, roof := r.split(values)
+		if len(floor) != 2 || len(sharedUnits(roof[0], roof[1])) == 0 {
+			return nil
+		}
+		extra := values[roof[0]].RemoveAll(r.pair())
+		if extra.Size() != 1 || values[roof[1]].RemoveAll(r.pair()) != extra {
+			return nil
+		}
+		elims := eliminationsSeeingAll(values, extra.SingleMemberDigit(), roof)
+		if len(elims) == 0 {
+			return nil
+		}
+		return rectangleStep(UniqueRectangle2, r, elims)
+	})
+}
+
+// findUniqueRectangle3 finds a rectangle with two roof corners in the same unit
+// whose extra candidates form a naked subset together with other squares in
+// that unit. Since one of the roof corners must hold an extra digit, the roof
+// acts as a single "virtual" square in the subset.
+func findUniqueRectangle3(values Values) *Step {
+	return forEachRectangle(values, func(r rectangle) *Step {
+		floor, roof := r.split(values)
+		if len(floor) != 2 {
+			return nil
+		}
+		extra := (values[roof[0]] | values[roof[1]]).RemoveAll(r.pair())
+
+		for _, u := range sharedUnits(roof[0], roof[1]) {
+			var others []int
+			for _, sq := range unitlist[u] {
+				if values[sq].Size() > 1 && !slices.Contains(roof, sq) {
+					others = append(others, sq)
+				}
+			}
+
+			for k := 1; k <= 3 && k < len(others); k++ {
+				var step *Step
+				forEachCombination(others, k, func(combo []int) bool {
+					union := extra
+					for _, sq := range combo {
+						union |= values[sq]
+					}
+					if union.Size() != k+1 {
+						return false
+					}
+					var elims []Candidate
+					for _, sq := range others {
+						if !slices.Contains(combo, sq) {
+							for _, digit := range digitsOf(values[sq] & union) {
+								elims = append(elims, Candidate{sq, digit})
+							}
+						}
+					}
+					if len(elims) == 0 {
+						return false
+					}
+					step = rectangleStep(UniqueRectangle3, r, elims)
+					describe(step, "deadly pattern %s in %s; extras %s form a naked subset with %s in %s",
+						r.pair(), squareNames(r.corners[:]), union, squareNames(combo), unitName(u))
+					return true
+				})
+				if step != nil {
+					return step
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// findUniqueRectangle4 finds a rectangle with two roof corners in the same unit,
+// where one of the rectangle's digits appears in that unit only in the roof
+// corners. That digit must be in one of them, so the other rectangle digit can
+// be eliminated from both.
+func findUniqueRectangle4(values Values) *Step {
+	return forEachRectangle(values, func(r rectangle) *Step {
+		floor, roof := r.split(values)
+		if len(floor) != 2 {
+			return nil
+		}
+		for _, u := range sharedUnits(roof[0], roof[1]) {
+			for _, ds := range [][2]uint16{{r.d1, r.d2}, {r.d2, r.d1}} {
+				locked, other := ds[0], ds[1]
+				if countInUnit(values, u, locked) == 2 {
+					elims := []Candidate{{roof[0], other}, {roof[1], other}}
+					return rectangleStep(UniqueRectangle4, r, elims)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// findUniqueRectangle5 finds a rectangle where two or three corners have
+// exactly one (identical) extra candidate, and the rest contain only the
+// rectangle digits. One of the corners with the extra digit must hold it, so
+// it can be eliminated from squares that see all of them. Type 2 is the
+// special case where the two roof corners share a line; this strategy handles
+// the remaining (diagonal and three-corner) cases.
+func findUniqueRectangle5(values Values) *Step {
+	return forEachRectangle(values, func(r rectangle) *Step {
+		floor, roof := r.split(values)
+		if len(floor) == 2 && len(sharedUnits(roof[0], roof[1])) > 0 {
+			return nil
+		}
+		if len(floor) != 1 && len(floor) != 2 {
+			return nil
+		}
+		extra := values[roof[0]].RemoveAll(r.pair())
+		if extra.Size() != 1 {
+			return nil
+		}
+		for _, sq := range roof[1:] {
+			if values[sq].RemoveAll(r.pair()) != extra {
+				return nil
+			}
+		}
+		elims := eliminationsSeeingAll(values, extra.SingleMemberDigit(), roof)
+		if len(elims) == 0 {
+			return nil
+		}
+		return rectangleStep(UniqueRectangle5, r, elims)
+	})
+}
+
+// findUniqueRectangle6 finds a rectangle with two diagonal floor corners, where
+// one of the rectangle digits is confined to the rectangle in both of its rows
+// and both of its columns. That digit would have to occupy either the floor
+// or the roof diagonal; the roof diagonal would be deadly, so the digit is
+// eliminated from both roof corners.
+func findUniqueRectangle6(values Values) *Step {
+	return forEachRectangle(values, func(r rectangle) *Step {
+		floor, roof := r.split(values)
+		if len(floor) != 2 || len(sharedUnits(roof[0], roof[1])) > 0 {
+			return nil
+		}
+		for _, digit := range []uint16{r.d1, r.d2} {
+			confined := true
+			for _, sq := range []Index{r.corners[0], r.corners[3]} {
+				if countInUnit(values, rowOf(sq), digit) != 2 || countInUnit(values, colOf(sq), digit) != 2 {
+					confined = false
+				}
+			}
+			if confined {
+				elims := []Candidate{{roof[0], digit}, {roof[1], digit}}
+				return rectangleStep(UniqueRectangle6, r, elims)
+			}
+		}
+		return nil
+	})
+}
+
+// countInUnit counts the squares in unit u that have digit as a candidate.
+func countInUnit(values Values, u int, digit uint16) int {
+	count := 0
+	for _, sq := range unitlist[u] {
+		if values[sq].IsMember(digit) {
+			count++
+		}
+	}
+	return count
+}
+
+// findBUGPlusOne applies the "Bivalue Universal Grave + 1" rule: if every
+// unsolved square has exactly two candidates except for a single square with
+// three, the board would have two solutions unless that square holds the digit
+// that appears three times in its units.
+func findBUGPlusOne(values Values) *Step {
+	extraSq := -1
+	for sq, d := range values {
+		switch d.Size() {
+		case 1, 2:
+		case 3:
+			if extraSq >= 0 {
+				return nil
+			}
+			extraSq = sq
+		default:
+			return nil
+		}
+	}
+	if extraSq < 0 {
+		return nil
+	}
+
+	// Find the digit of the extra square that appears three times in each of
+	// its units.
+	var bugDigit uint16
+	for _, digit := range digitsOf(values[extraSq]) {
+		if countUnsolvedInUnit(values, rowOf(extraSq), digit) == 3 &&
+			countUnsolvedInUnit(values, colOf(extraSq), digit) == 3 &&
+			countUnsolvedInUnit(values, boxOf(extraSq), digit) == 3 {
+			if bugDigit != 0 {
+				return nil
+			}
+			bugDigit = digit
+		}
+	}
+	if bugDigit == 0 {
+		return nil
+	}
+
+	// Check that without bugDigit in the extra square, every unsolved digit
+	// appears exactly twice in every unit.
+	for u, unit := range unitlist {
+		for digit := uint16(1); digit <= 9; digit++ {
+			count := countUnsolvedInUnit(values, u, digit)
+			if digit == bugDigit && slices.Contains(unit, extraSq) {
+				count--
+			}
+			if count != 0 && count != 2 {
+				return nil
+			}
+		}
+	}
+
+	step := &Step{
+		Technique:  BUGPlusOne,
+		Placements: []Candidate{{extraSq, bugDigit}},
+		Cells:      []Index{extraSq},
+		Digits:     SingleDigitSet(bugDigit),
+	}
+	return describe(step, "all unsolved squares except %s are bivalue", squareName(extraSq))
+}
+
+// countUnsolvedInUnit counts the unsolved squares in unit u that have digit as
+// a candidate.
+func countUnsolvedInUnit(values Values, u int, digit uint16) int {
+	count := 0
+	for _, sq := range unitlist[u] {
+		if values[sq].Size() > 1 && values[sq].IsMember(digit) {
+			count++
+		}
+	}
+	return count
+}
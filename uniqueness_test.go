@@ -0,0 +1,139 @@
+package sudoku
+
+import (
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+// rectangleBoard creates an empty board where the corners of the rectangle on
+// squares 0, 3, 9 and 12 (rows 1-2, columns 1 and 4) have the given
+// candidates.
+func rectangleBoard(c0, c3, c9, c12 Digits) Values {
+	v := EmptyBoard()
+	v[0], v[3], v[9], v[12] = c0, c3, c9, c12
+	return v
+}
+
+var d12 = Digits(0).Add(1).Add(2)
+
+func checkRectangleStep(t *testing.T, step *Step, want Technique, wantElims []Candidate) {
+	t.Helper()
+	if step == nil {
+		t.Fatalf("got no step, want %v", want)
+	}
+	if step.Technique != want {
+		t.Errorf("got technique %v, want %v", step.Technique, want)
+	}
+	if !slices.Equal(step.Cells, []Index{0, 3, 9, 12}) || step.Digits != d12 {
+		t.Errorf("got deadly pattern %v %v", step.Cells, step.Digits)
+	}
+	if !slices.Equal(step.Eliminations, wantElims) {
+		t.Errorf("got eliminations %v, want %v", step.Eliminations, wantElims)
+	}
+}
+
+func TestUniqueRectangle1(t *testing.T) {
+	v := rectangleBoard(d12, d12, d12, d12.Add(5))
+	checkRectangleStep(t, findUniqueRectangle1(v), UniqueRectangle1, []Candidate{{12, 1}, {12, 2}})
+
+	// Rectangles within a single box aren't deadly patterns.
+	v = EmptyBoard()
+	v[0], v[1], v[9], v[10] = d12, d12, d12, d12.Add(5)
+	if step := findUniqueRectangle1(v); step != nil {
+		t.Errorf("got step %q for rectangle in a single box", step.Description)
+	}
+}
+
+func TestUniqueRectangle2(t *testing.T) {
+	v := rectangleBoard(d12, d12, d12.Add(5), d12.Add(5))
+	var want []Candidate
+	for _, sq := range []Index{10, 11, 13, 14, 15, 16, 17} {
+		want = append(want, Candidate{sq, 5})
+	}
+	checkRectangleStep(t, findUniqueRectangle2(v), UniqueRectangle2, want)
+}
+
+func TestUniqueRectangle3(t *testing.T) {
+	v := rectangleBoard(d12, d12, d12.Add(5), d12.Add(6))
+	v[10] = Digits(0).Add(5).Add(6)
+	var want []Candidate
+	for _, sq := range []Index{11, 13, 14, 15, 16, 17} {
+		want = append(want, Candidate{sq, 5}, Candidate{sq, 6})
+	}
+	checkRectangleStep(t, findUniqueRectangle3(v), UniqueRectangle3, want)
+}
+
+func TestUniqueRectangle4(t *testing.T) {
+	v := rectangleBoard(d12, d12, d12.Add(5), d12.Add(6))
+	for sq := 9; sq < 18; sq++ {
+		if sq != 9 && sq != 12 {
+			v[sq] = v[sq].Remove(1)
+		}
+	}
+	checkRectangleStep(t, findUniqueRectangle4(v), UniqueRectangle4, []Candidate{{9, 2}, {12, 2}})
+}
+
+func TestUniqueRectangle5(t *testing.T) {
+	d125 := d12.Add(5)
+	v := rectangleBoard(d12, d125, d125, d125)
+	checkRectangleStep(t, findUniqueRectangle5(v), UniqueRectangle5, []Candidate{{13, 5}, {14, 5}})
+
+	// With the roof in a single row, this is type 2 rather than type 5.
+	v = rectangleBoard(d12, d12, d125, d125)
+	if step := findUniqueRectangle5(v); step != nil {
+		t.Errorf("got type 5 step %q for a type 2 pattern", step.Description)
+	}
+}
+
+func TestUniqueRectangle6(t *testing.T) {
+	v := rectangleBoard(d12, d12.Add(5), d12.Add(6), d12)
+	for _, sq := range append(slices.Clone(unitlist[0]), unitlist[1]...) {
+		if sq%9 != 0 && sq%9 != 3 {
+			v[sq] = v[sq].Remove(1)
+		}
+	}
+	for _, sq := range append(slices.Clone(unitlist[9]), unitlist[12]...) {
+		if sq/9 > 1 {
+			v[sq] = v[sq].Remove(1)
+		}
+	}
+	checkRectangleStep(t, findUniqueRectangle6(v), UniqueRectangle6, []Candidate{{3, 1}, {9, 1}})
+}
+
+func TestBUGPlusOne(t *testing.T) {
+	for _, board := range readInputBoards(t, "inputs/norvig-hard.txt") {
+		v, err := ParseBoard(board, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, steps, _ := SolveLogically(v, LogicOptions{AssumeUnique: true})
+		for i, step := range steps {
+			if step.Technique != BUGPlusOne {
+				continue
+			}
+
+			// Replay the steps up to the BUG+1 and check the position it was found
+			// in.
+			vs := slices.Clone(v)
+			removeSolvedFromPeers(vs)
+			for _, s := range steps[:i] {
+				ApplyStep(vs, s)
+			}
+			sq := step.Placements[0].Square
+			if vs[sq].Size() != 3 {
+				t.Errorf("got BUG+1 square with candidates %s", vs[sq])
+			}
+			for other, d := range vs {
+				if other != sq && d.Size() > 2 {
+					t.Errorf("got non-bivalue square %v in BUG+1 position", other)
+				}
+			}
+			if findBUGPlusOne(vs) == nil {
+				t.Errorf("findBUGPlusOne found nothing in replayed position")
+			}
+			return
+		}
+	}
+	t.Errorf("found no BUG+1 step in inputs")
+}